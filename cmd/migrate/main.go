@@ -0,0 +1,40 @@
+// Command migrate upgrades data stored in Firebase to the current layout.
+package main
+
+import (
+	"log"
+
+	"github.com/Ygnas/FoodLog/controllers"
+)
+
+func main() {
+	if err := controllers.GetFirebaseDatabase().FirebaseConnect(); err != nil {
+		log.Fatalf("Could not connect to Firebase: %v\n", err)
+	}
+
+	storage := controllers.NewStorage()
+
+	migrated, err := storage.MigrateLikesToReactions()
+	if err != nil {
+		log.Fatalf("Migrating likes to reactions failed: %v\n", err)
+	}
+	log.Printf("Migrated likes of %d listings to reactions\n", migrated)
+
+	assigned, err := storage.AssignMissingHandles()
+	if err != nil {
+		log.Fatalf("Assigning handles failed: %v\n", err)
+	}
+	log.Printf("Assigned handles to %d users\n", assigned)
+
+	rekeyed, err := storage.RekeyUsersByID()
+	if err != nil {
+		log.Fatalf("Rekeying users by ID failed: %v\n", err)
+	}
+	log.Printf("Rekeyed %d users by ID\n", rekeyed)
+
+	indexed, err := storage.IndexListings()
+	if err != nil {
+		log.Fatalf("Indexing listings failed: %v\n", err)
+	}
+	log.Printf("Indexed %d listings\n", indexed)
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+func BlockUser(w http.ResponseWriter, r *http.Request) {
+	setRelation(w, r, (*Storage).Block, "User blocked")
+}
+
+func UnblockUser(w http.ResponseWriter, r *http.Request) {
+	setRelation(w, r, (*Storage).Unblock, "User unblocked")
+}
+
+func MuteUser(w http.ResponseWriter, r *http.Request) {
+	setRelation(w, r, (*Storage).Mute, "User muted")
+}
+
+func UnmuteUser(w http.ResponseWriter, r *http.Request) {
+	setRelation(w, r, (*Storage).Unmute, "User unmuted")
+}
+
+func setRelation(w http.ResponseWriter, r *http.Request, set func(*Storage, string, string) error, message string) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	handle := chi.URLParam(r, "handle")
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	user, err := storage.GetUserByHandle(handle)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = set(storage, claims["id"].(string), user.ID.String())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Write([]byte(message))
+}
+
+// GetBlocked lists the users the authenticated user has blocked.
+func GetBlocked(w http.ResponseWriter, r *http.Request) {
+	listRelations(w, r, (*Storage).GetBlocked)
+}
+
+// GetMuted lists the users the authenticated user has muted.
+func GetMuted(w http.ResponseWriter, r *http.Request) {
+	listRelations(w, r, (*Storage).GetMuted)
+}
+
+func listRelations(w http.ResponseWriter, r *http.Request, edges func(*Storage, string) ([]string, error)) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	limit, offset, ok := parsePage(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	ids, err := edges(storage, claims["id"].(string))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeUserPage(w, storage, ids, limit, offset)
+}
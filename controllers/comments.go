@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Ygnas/FoodLog/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+func CommentListing(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	var comment models.Comment
+
+	err = json.NewDecoder(r.Body).Decode(&comment)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	created, err := storage.CommentListing(id, claims["id"].(string), comment)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(created)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
+
+func GetComments(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	limit, offset, ok := parsePage(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	comments, err := storage.GetComments(id, claims["id"].(string))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(models.NewPage(comments, limit, offset))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
+
+func UpdateComment(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	commentID := chi.URLParam(r, "commentId")
+
+	var comment models.Comment
+
+	err = json.NewDecoder(r.Body).Decode(&comment)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	updated, err := storage.UpdateComment(id, commentID, claims["id"].(string), comment.Comment)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(updated)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
+
+func DeleteComment(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	commentID := chi.URLParam(r, "commentId")
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	err = storage.DeleteComment(id, commentID, claims["id"].(string))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Write([]byte("Comment deleted"))
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"log"
+	"os"
+
+	"github.com/Ygnas/FoodLog/filter"
+)
+
+var contentFilter *filter.Pipeline
+
+// NewContentFilter builds the filter listings and comments are screened with, from the
+// JSON config file named by FILTER_CONFIG or from the defaults.
+func NewContentFilter() *filter.Pipeline {
+	config := filter.DefaultConfig
+	if path := os.Getenv("FILTER_CONFIG"); path != "" {
+		loaded, err := filter.LoadConfig(path)
+		if err != nil {
+			log.Printf("Could not load filter config, using defaults: %v\n", err)
+		} else {
+			config = loaded
+		}
+	}
+
+	pipeline, err := filter.New(config)
+	if err != nil {
+		log.Printf("Invalid filter config, using defaults: %v\n", err)
+		pipeline, _ = filter.New(filter.DefaultConfig)
+	}
+	contentFilter = pipeline
+	return pipeline
+}
+
+func GetContentFilter() *filter.Pipeline {
+	return contentFilter
+}
+
+// SetContentFilter replaces the content filter. A nil filter lets everything through.
+func SetContentFilter(pipeline *filter.Pipeline) {
+	contentFilter = pipeline
+}
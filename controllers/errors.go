@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Ygnas/FoodLog/filter"
+	"github.com/Ygnas/FoodLog/foods"
+	"github.com/Ygnas/FoodLog/models"
+)
+
+var errPreconditionRequired = errors.New("If-Match header is required")
+
+// writeError maps errors returned by Storage and request validation onto HTTP responses.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrListingNotFound), errors.Is(err, ErrUserNotFound), errors.Is(err, ErrImageNotFound),
+		errors.Is(err, ErrCommentNotFound), errors.Is(err, foods.ErrFoodNotFound), errors.Is(err, foods.ErrProductNotFound):
+		http.Error(w, "Not Found", http.StatusNotFound)
+	case errors.Is(err, models.ErrInvalidListing), errors.Is(err, models.ErrInvalidComment),
+		errors.Is(err, models.ErrInvalidReaction), errors.Is(err, ErrCannotFollowSelf),
+		errors.Is(err, models.ErrInvalidReport), errors.Is(err, models.ErrInvalidAction),
+		errors.Is(err, models.ErrInvalidNutrition), errors.Is(err, models.ErrInvalidIngredient),
+		errors.Is(err, models.ErrInvalidGoals), errors.Is(err, models.ErrInvalidMealWindows),
+		errors.Is(err, ErrCannotTargetSelf), errors.Is(err, foods.ErrInvalidBarcode), errors.Is(err, foods.ErrInvalidDataset):
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+	case errors.Is(err, models.ErrInvalidHandle), errors.Is(err, models.ErrInvalidTimeZone):
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+	case errors.Is(err, ErrHandleTaken), errors.Is(err, ErrEmailTaken):
+		http.Error(w, "Conflict", http.StatusConflict)
+	case errors.Is(err, filter.ErrRejected), errors.Is(err, models.ErrUnknownMealType):
+		http.Error(w, "Unprocessable Entity", http.StatusUnprocessableEntity)
+	case errors.Is(err, errPreconditionRequired):
+		http.Error(w, "Precondition Required", http.StatusPreconditionRequired)
+	case errors.Is(err, ErrVersionMismatch):
+		http.Error(w, "Precondition Failed", http.StatusPreconditionFailed)
+	case errors.Is(err, ErrListingNotVisible), errors.Is(err, ErrCommentNotEditable), errors.Is(err, ErrBlocked),
+		errors.Is(err, ErrNotModerator), errors.Is(err, ErrNotAdmin), errors.Is(err, ErrNotAccountOwner),
+		errors.Is(err, ErrNotListingOwner):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	default:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Ygnas/FoodLog/models"
+)
+
+// anyVersion is returned by ifMatchVersion for "If-Match: *", which matches any
+// existing listing.
+const anyVersion = -1
+
+// listingETag returns the strong entity tag of a listing, derived from its version.
+func listingETag(listing *models.Listing) string {
+	return `"` + strconv.FormatInt(listing.Version, 10) + `"`
+}
+
+// bodyETag returns a strong entity tag for a response body that has no version of its own.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ifMatchVersion parses the If-Match header into the listing version the client
+// expects. A missing header is errPreconditionRequired; a tag that is not a listing
+// version can never match and is reported as ErrVersionMismatch.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, errPreconditionRequired
+	}
+	if header == "*" {
+		return anyVersion, nil
+	}
+
+	if len(header) < 2 || !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) {
+		return 0, ErrVersionMismatch
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version < 0 {
+		return 0, ErrVersionMismatch
+	}
+	return version, nil
+}
+
+// notModified reports whether the If-None-Match header matches etag.
+func notModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeWithETag writes body with the given entity tag, answering 304 Not Modified
+// instead when the client already holds that representation.
+func writeWithETag(w http.ResponseWriter, r *http.Request, etag string, body []byte) {
+	w.Header().Set("ETag", etag)
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(body)
+}
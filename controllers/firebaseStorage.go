@@ -2,11 +2,41 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	gcs "cloud.google.com/go/storage"
+	"firebase.google.com/go/v4/db"
+	"github.com/Ygnas/FoodLog/filter"
+	"github.com/Ygnas/FoodLog/foods"
 	"github.com/Ygnas/FoodLog/models"
 	"github.com/Ygnas/FoodLog/util"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrListingNotFound    = errors.New("listing not found")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrImageNotFound      = errors.New("image not found")
+	ErrListingNotVisible  = errors.New("listing is not visible to this user")
+	ErrVersionMismatch    = errors.New("listing version does not match")
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrCannotFollowSelf   = errors.New("users cannot follow themselves")
+	ErrCannotTargetSelf   = errors.New("users cannot block or mute themselves")
+	ErrBlocked            = errors.New("the listing owner has blocked this user")
+	ErrNotModerator       = errors.New("only moderators may do this")
+	ErrNotAdmin           = errors.New("only admins may do this")
+	ErrNotAccountOwner    = errors.New("users may only delete their own account")
+	ErrNotListingOwner    = errors.New("only the listing owner may do this")
+	ErrHandleTaken        = errors.New("handle is already taken")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrCommentNotEditable = errors.New("only the comment author or listing owner may change a comment")
 )
 
 type Storage struct {
@@ -20,66 +50,629 @@ func NewStorage() *Storage {
 	}
 }
 
-func (s *Storage) Create(emailHash string, listing *models.Listing) error {
-	listing.UserEmail = util.Base64Decode(emailHash)
-	if err := s.NewRef("listings/").Child(emailHash).Child(listing.ID.String()).Set(context.Background(), listing); err != nil {
+func (s *Storage) Create(userID string, listing *models.Listing) error {
+	owner, err := s.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	var flagged *filter.Result
+	if text := listingText(listing); text != "" {
+		flagged = GetContentFilter().Run(&filter.Content{
+			UserID: userID,
+			Kind:   models.KindListing,
+			Text:   text,
+		})
+	}
+	if flagged != nil && flagged.Action == filter.Reject {
+		return fmt.Errorf("%w: %s", filter.ErrRejected, flagged.Rule)
+	}
+
+	listing.UserID = userID
+	listing.UserHandle = owner.Handle
+	listing.Hidden = false
+	listing.Version = 1
+	// Reactions and comments are only ever added through their own endpoints, which
+	// attribute them to the caller, and the counts are computed for responses.
+	listing.Reactions = nil
+	listing.Comments = nil
+	listing.ClearSummary()
+	if listing.EatenAt == nil {
+		eatenAt := listing.CreatedAt.In(owner.Location())
+		listing.EatenAt = &eatenAt
+	}
+	// The meal type is inferred from the local time of day the meal was eaten, in
+	// the offset it was given with.
+	if listing.Type == "" {
+		listing.Type = owner.MealWindows.Infer(*listing.EatenAt)
+	}
+	// The listing and its index entry are written in one multi-path update so the
+	// index never points at a listing that does not exist.
+	if err := s.NewRef("/").Update(context.Background(), map[string]interface{}{
+		"listings/" + userID + "/" + listing.ID.String(): listing,
+		"listing-index/" + listing.ID.String():           userID,
+	}); err != nil {
 		return err
 	}
+
+	if flagged != nil {
+		return s.flagContent(models.KindListing, listing.ID.String(), listing.ID.String(), flagged)
+	}
 	return nil
 }
 
-func (s *Storage) Delete(emailHash string, id string) error {
-	return s.NewRef("listings/").Child(emailHash).Child(id).Delete(context.Background())
+// Delete removes a listing if its version matches. Pass anyVersion to skip the check.
+// The index entries and reports of the listing as it was deleted are removed after it
+// in one multi-path update.
+func (s *Storage) Delete(userID string, id string, version int64) error {
+	var deleted models.Listing
+	err := s.NewRef("listings/").Child(userID).Child(id).Transaction(context.Background(), func(node db.TransactionNode) (interface{}, error) {
+		deleted = models.Listing{}
+		if err := node.Unmarshal(&deleted); err != nil {
+			return nil, err
+		}
+		if deleted.ID == uuid.Nil {
+			return nil, ErrListingNotFound
+		}
+		if version != anyVersion && deleted.Version != version {
+			return nil, ErrVersionMismatch
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.NewRef("/").Update(context.Background(), listingCleanupUpdates(&deleted))
+}
+
+// deleteListingUpdates are the updates removing a listing together with its index
+// entries and reports.
+func deleteListingUpdates(listing *models.Listing) map[string]interface{} {
+	updates := listingCleanupUpdates(listing)
+	updates[listingPath(listing)] = nil
+	return updates
+}
+
+// listingCleanupUpdates are the updates removing the index entries of a listing and its
+// comments, and the reports against them.
+func listingCleanupUpdates(listing *models.Listing) map[string]interface{} {
+	id := listing.ID.String()
+	updates := map[string]interface{}{
+		"listing-index/" + id:                              nil,
+		"reports/" + string(models.KindListing) + "/" + id: nil,
+	}
+	for commentID := range listing.Comments {
+		updates["comment-index/"+commentID] = nil
+		updates["reports/"+string(models.KindComment)+"/"+commentID] = nil
+	}
+	return updates
 }
 
-func (s *Storage) GetListing(emailHash string, id string) (*models.Listing, error) {
+func (s *Storage) GetListing(userID string, id string) (*models.Listing, error) {
 	var listing models.Listing
-	if err := s.NewRef("listings/").Child(emailHash).Child(id).Get(context.Background(), &listing); err != nil {
+	if err := s.NewRef("listings/").Child(userID).Child(id).Get(context.Background(), &listing); err != nil {
 		return nil, err
 	}
+	if listing.ID == uuid.Nil {
+		return nil, ErrListingNotFound
+	}
 	return &listing, nil
 }
 
-func (s *Storage) GetAllUserListings(emailHash string) ([]*models.Listing, error) {
+// GetListingOwner resolves the ID of the user owning the listing with the given id
+// through the listing index. Listings created before the index existed are indexed by
+// the migrate command.
+func (s *Storage) GetListingOwner(id string) (string, error) {
+	var userID string
+	if err := s.NewRef("listing-index").Child(id).Get(context.Background(), &userID); err != nil {
+		return "", err
+	}
+	if userID == "" {
+		return "", ErrListingNotFound
+	}
+	return userID, nil
+}
+
+// IndexListings adds the listings missing from the listing index to it.
+func (s *Storage) IndexListings() (int, error) {
+	var index map[string]string
+	if err := s.NewRef("listing-index").Get(context.Background(), &index); err != nil {
+		return 0, err
+	}
+	var owners map[string]bool
+	if err := s.NewRef("listings").GetShallow(context.Background(), &owners); err != nil {
+		return 0, err
+	}
+
+	updates := map[string]interface{}{}
+	for ownerID := range owners {
+		var listingIDs map[string]bool
+		if err := s.NewRef("listings").Child(ownerID).GetShallow(context.Background(), &listingIDs); err != nil {
+			return 0, err
+		}
+		for id := range listingIDs {
+			if index[id] != ownerID {
+				updates["listing-index/"+id] = ownerID
+			}
+		}
+	}
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	return len(updates), s.NewRef("/").Update(context.Background(), updates)
+}
+
+// GetListingByID looks up a listing by id regardless of who owns it.
+func (s *Storage) GetListingByID(id string) (*models.Listing, error) {
+	userID, err := s.GetListingOwner(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetListing(userID, id)
+}
+
+func (s *Storage) GetAllUserListings(userID string) ([]*models.Listing, error) {
 	var listingsMap map[string]*models.Listing
 
-	if err := s.NewRef("listings").Child(emailHash).Get(context.Background(), &listingsMap); err != nil {
+	if err := s.NewRef("listings").Child(userID).Get(context.Background(), &listingsMap); err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
 	var listings []*models.Listing
 	for _, listing := range listingsMap {
+		if listing.ID == uuid.Nil {
+			continue
+		}
 		listings = append(listings, listing)
 	}
 
 	return listings, nil
 }
 
-func (s *Storage) UpdateListing(emailHash string, listing *models.Listing) error {
-	if err := s.NewRef("listings/").Child(emailHash).Child(listing.ID.String()).Set(context.Background(), listing); err != nil {
-		return err
+// UpdateListing applies update to the stored listing inside a transaction and returns
+// the result with its version bumped. It never creates one: updating an id that is not
+// stored returns ErrListingNotFound, and a stale version returns ErrVersionMismatch.
+// Pass anyVersion to skip the version check.
+func (s *Storage) UpdateListing(userID string, id string, version int64, update func(*models.Listing) error) (*models.Listing, error) {
+	var updated models.Listing
+	err := s.NewRef("listings/").Child(userID).Child(id).Transaction(context.Background(), func(node db.TransactionNode) (interface{}, error) {
+		updated = models.Listing{}
+		if err := node.Unmarshal(&updated); err != nil {
+			return nil, err
+		}
+		if updated.ID == uuid.Nil {
+			return nil, ErrListingNotFound
+		}
+		if version != anyVersion && updated.Version != version {
+			return nil, ErrVersionMismatch
+		}
+		if err := update(&updated); err != nil {
+			return nil, err
+		}
+		updated.ClearSummary()
+		updated.Version++
+		return &updated, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return &updated, nil
+}
+
+// EditListing applies a user's edit to a listing like UpdateListing. When the edit
+// changes the title or description, the new text is run through the content filter.
+func (s *Storage) EditListing(userID string, id string, version int64, edit func(*models.Listing) error) (*models.Listing, error) {
+	// The transaction may run the edit more than once, so the filter only runs once
+	// per text to keep retries from counting as repeated posts.
+	var screened string
+	var flagged *filter.Result
+	ran, changed := false, false
+
+	listing, err := s.UpdateListing(userID, id, version, func(listing *models.Listing) error {
+		before := listingText(listing)
+		if err := edit(listing); err != nil {
+			return err
+		}
+
+		after := listingText(listing)
+		changed = after != before && after != ""
+		if !changed {
+			return nil
+		}
+		if !ran || after != screened {
+			ran, screened = true, after
+			flagged = GetContentFilter().Run(&filter.Content{
+				UserID: userID,
+				Kind:   models.KindListing,
+				Text:   after,
+			})
+		}
+		if flagged != nil && flagged.Action == filter.Reject {
+			return fmt.Errorf("%w: %s", filter.ErrRejected, flagged.Rule)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed && flagged != nil {
+		if err := s.flagContent(models.KindListing, id, id, flagged); err != nil {
+			return nil, err
+		}
+	}
+	return listing, nil
+}
+
+// listingText is the user-written text of a listing the content filter screens. It is
+// empty for private listings, which only their owner sees, and for listings without
+// text, so neither is rejected as a repeated post nor shown to moderators.
+func listingText(listing *models.Listing) string {
+	if listing.Visibility == models.Private {
+		return ""
+	}
+	return strings.TrimSpace(listing.Title + "\n" + listing.Description)
 }
 
+// maxHandleAttempts bounds how many numbered variants of a derived handle are tried.
+const maxHandleAttempts = 100
+
+// RegisterUser stores a new user under their ID. The email must not be registered
+// yet. A requested handle must be free; without one, a handle is derived from the
+// email and numbered until it is unique.
 func (s *Storage) RegisterUser(user *models.User) error {
-	if err := s.NewRef("users/"+util.Base64Encode(user.Email)).Set(context.Background(), user); err != nil {
+	userID := user.ID.String()
+	emailHash := util.Base64Encode(user.Email)
+
+	err := s.NewRef("emails").Child(emailHash).Transaction(context.Background(), func(node db.TransactionNode) (interface{}, error) {
+		var owner string
+		if err := node.Unmarshal(&owner); err != nil {
+			return nil, err
+		}
+		if owner != "" {
+			return nil, ErrEmailTaken
+		}
+		return userID, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if user.Handle != "" {
+		err = s.ClaimHandle(user.Handle, userID)
+	} else {
+		user.Handle, err = s.claimDerivedHandle(user.Email, userID)
+	}
+	if err != nil {
+		s.NewRef("emails").Child(emailHash).Delete(context.Background())
+		return err
+	}
+
+	if err := s.NewRef("users/"+userID).Set(context.Background(), user); err != nil {
 		return err
 	}
 	return nil
 
 }
 
+// ClaimHandle reserves handle for user userID, failing with
+// ErrHandleTaken if another user holds it.
+func (s *Storage) ClaimHandle(handle string, userID string) error {
+	return s.NewRef("handles").Child(handle).Transaction(context.Background(), func(node db.TransactionNode) (interface{}, error) {
+		var owner string
+		if err := node.Unmarshal(&owner); err != nil {
+			return nil, err
+		}
+		if owner != "" && owner != userID {
+			return nil, ErrHandleTaken
+		}
+		return userID, nil
+	})
+}
+
+func (s *Storage) claimDerivedHandle(email string, userID string) (string, error) {
+	base := models.HandleFromEmail(email)
+	for attempt := 1; attempt <= maxHandleAttempts; attempt++ {
+		handle := base
+		if attempt > 1 {
+			handle = base + strconv.Itoa(attempt)
+		}
+
+		err := s.ClaimHandle(handle, userID)
+		if errors.Is(err, ErrHandleTaken) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return handle, nil
+	}
+	return "", ErrHandleTaken
+}
+
+// GetUserByHandle looks up a user by their public handle.
+func (s *Storage) GetUserByHandle(handle string) (*models.User, error) {
+	var userID string
+	if err := s.NewRef("handles").Child(handle).Get(context.Background(), &userID); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, ErrUserNotFound
+	}
+	return s.GetUser(userID)
+}
+
+// UpdateProfile changes the public profile fields of user userID.
+func (s *Storage) UpdateProfile(userID string, name string, bio string, avatarURL string, timeZone string) (*models.User, error) {
+	if _, err := s.GetUser(userID); err != nil {
+		return nil, err
+	}
+
+	err := s.NewRef("users").Child(userID).Update(context.Background(), map[string]interface{}{
+		"name":       name,
+		"bio":        bio,
+		"avatar_url": avatarURL,
+		"time_zone":  timeZone,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetUser(userID)
+}
+
+// SetGoals replaces the daily goals of user userID. Empty goals clear them.
+func (s *Storage) SetGoals(userID string, goals models.Goals) (*models.User, error) {
+	return s.setUserSetting(userID, "goals", goals, len(goals) == 0)
+}
+
+// SetMealWindows replaces the meal windows of user userID. Empty windows restore the
+// defaults.
+func (s *Storage) SetMealWindows(userID string, windows models.MealWindows) (*models.User, error) {
+	return s.setUserSetting(userID, "meal_windows", windows, len(windows) == 0)
+}
+
+// setUserSetting sets the field key of user userID to value, or deletes it when empty.
+func (s *Storage) setUserSetting(userID string, key string, value interface{}, empty bool) (*models.User, error) {
+	if _, err := s.GetUser(userID); err != nil {
+		return nil, err
+	}
+
+	ref := s.NewRef("users").Child(userID).Child(key)
+	var err error
+	if empty {
+		err = ref.Delete(context.Background())
+	} else {
+		err = ref.Set(context.Background(), value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetUser(userID)
+}
+
+// AssignMissingHandles gives every user registered before handles existed a derived handle.
+func (s *Storage) AssignMissingHandles() (int, error) {
+	var users map[string]*models.User
+	if err := s.NewRef("users").Get(context.Background(), &users); err != nil {
+		return 0, err
+	}
+
+	assigned := 0
+	for userID, user := range users {
+		if user.Handle != "" {
+			continue
+		}
+		handle, err := s.claimDerivedHandle(user.Email, userID)
+		if err != nil {
+			return assigned, err
+		}
+		if err := s.NewRef("users").Child(userID).Child("handle").Set(context.Background(), handle); err != nil {
+			return assigned, err
+		}
+		assigned++
+	}
+	return assigned, nil
+}
+
+// RekeyUsersByID moves users stored under the base64 hash of their email to their
+// user ID, along with everything keyed by or holding that hash or the email itself:
+// listings and the listing index, follow edges, handles, reactions, comment authors
+// and shared_with entries. Users already stored by ID are left alone, so it is safe
+// to run more than once.
+func (s *Storage) RekeyUsersByID() (int, error) {
+	var users map[string]*models.User
+	if err := s.NewRef("users").Get(context.Background(), &users); err != nil {
+		return 0, err
+	}
+
+	updates := map[string]interface{}{}
+	idByHash := make(map[string]string)
+	handleByID := make(map[string]string)
+	rekeyed := 0
+	for key, user := range users {
+		emailHash := util.Base64Encode(user.Email)
+		if key != emailHash {
+			idByHash[emailHash] = key
+			handleByID[key] = user.Handle
+			continue
+		}
+
+		if user.ID == uuid.Nil {
+			user.ID = uuid.New()
+		}
+		userID := user.ID.String()
+		idByHash[emailHash] = userID
+		handleByID[userID] = user.Handle
+
+		updates["users/"+key] = nil
+		updates["users/"+userID] = user
+		updates["emails/"+emailHash] = userID
+		if user.Handle != "" {
+			updates["handles/"+user.Handle] = userID
+		}
+		rekeyed++
+	}
+
+	rekey := func(key string) string {
+		if id, ok := idByHash[key]; ok {
+			return id
+		}
+		return key
+	}
+
+	var listingsMap map[string]map[string]map[string]interface{}
+	if err := s.NewRef("listings").Get(context.Background(), &listingsMap); err != nil {
+		return 0, err
+	}
+	for ownerKey, userListings := range listingsMap {
+		ownerID := rekey(ownerKey)
+		if ownerID != ownerKey {
+			updates["listings/"+ownerKey] = nil
+		}
+		for id, listing := range userListings {
+			changed := rekeyListing(listing, ownerID, handleByID[ownerID], idByHash)
+			if changed || ownerID != ownerKey {
+				updates["listings/"+ownerID+"/"+id] = listing
+				updates["listing-index/"+id] = ownerID
+			}
+		}
+	}
+
+	for _, path := range []string{"followers", "following"} {
+		var edges map[string]map[string]bool
+		if err := s.NewRef(path).Get(context.Background(), &edges); err != nil {
+			return 0, err
+		}
+		for key, users := range edges {
+			changed := rekey(key) != key
+			rekeyedUsers := make(map[string]bool, len(users))
+			for user, ok := range users {
+				changed = changed || rekey(user) != user
+				rekeyedUsers[rekey(user)] = ok
+			}
+			if !changed {
+				continue
+			}
+			if rekey(key) != key {
+				updates[path+"/"+key] = nil
+			}
+			updates[path+"/"+rekey(key)] = rekeyedUsers
+		}
+	}
+
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	if err := s.NewRef("/").Update(context.Background(), updates); err != nil {
+		return 0, err
+	}
+	return rekeyed, nil
+}
+
+// rekeyListing rewrites a raw stored listing owned by ownerID so that it refers to
+// users by ID instead of by email or email hash. It reports whether anything changed.
+func rekeyListing(listing map[string]interface{}, ownerID string, ownerHandle string, idByHash map[string]string) bool {
+	changed := false
+	if listing["user_id"] != ownerID {
+		listing["user_id"] = ownerID
+		listing["user_handle"] = ownerHandle
+		changed = true
+	}
+	if _, ok := listing["user_email"]; ok {
+		delete(listing, "user_email")
+		changed = true
+	}
+
+	if reactions, ok := listing["reactions"].(map[string]interface{}); ok {
+		rekeyed := make(map[string]interface{}, len(reactions))
+		for key, value := range reactions {
+			reaction, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			userID := key
+			if id, ok := idByHash[key]; ok {
+				userID = id
+			}
+			if _, ok := reaction["email"]; ok || userID != key || reaction["user_id"] != userID {
+				delete(reaction, "email")
+				reaction["user_id"] = userID
+				changed = true
+			}
+			rekeyed[userID] = reaction
+		}
+		listing["reactions"] = rekeyed
+	}
+
+	comments := map[string]interface{}{}
+	switch stored := listing["comments"].(type) {
+	case map[string]interface{}:
+		comments = stored
+	case []interface{}:
+		for index, comment := range stored {
+			if comment != nil {
+				comments[strconv.Itoa(index)] = comment
+			}
+		}
+		listing["comments"] = comments
+		changed = true
+	}
+	for _, value := range comments {
+		comment, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if email, ok := comment["email"].(string); ok {
+			comment["user_id"] = idByHash[util.Base64Encode(email)]
+			delete(comment, "email")
+			changed = true
+		}
+	}
+
+	if sharedWith, ok := listing["shared_with"].([]interface{}); ok {
+		var rekeyed []interface{}
+		for _, value := range sharedWith {
+			shared, _ := value.(string)
+			if !strings.Contains(shared, "@") {
+				rekeyed = append(rekeyed, shared)
+				continue
+			}
+			// Emails nobody registered with are dropped rather than kept in the clear.
+			if id, ok := idByHash[util.Base64Encode(shared)]; ok {
+				rekeyed = append(rekeyed, id)
+			}
+			changed = true
+		}
+		listing["shared_with"] = rekeyed
+	}
+	return changed
+}
+
+func (s *Storage) GetUser(userID string) (*models.User, error) {
+	var user models.User
+	if err := s.NewRef("users").Child(userID).Get(context.Background(), &user); err != nil {
+		return nil, err
+	}
+	if user.Email == "" {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
 func (s *Storage) LoginUser(user *models.User) (*models.User, error) {
-	var returnedUser models.User
-	if err := s.NewRef("users/"+util.Base64Encode(user.Email)).Get(context.Background(), &returnedUser); err != nil {
+	var userID string
+	if err := s.NewRef("emails").Child(util.Base64Encode(user.Email)).Get(context.Background(), &userID); err != nil {
 		return nil, err
 	}
-	return &returnedUser, nil
+	if userID == "" {
+		return nil, ErrUserNotFound
+	}
+	return s.GetUser(userID)
 }
 
-func (s *Storage) GetAllListings() ([]*models.Listing, error) {
+func (s *Storage) GetAllListings(userID string) ([]*models.Listing, error) {
 	var listingsMap map[string]map[string]*models.Listing
 
 	if err := s.NewRef("listings").Get(context.Background(), &listingsMap); err != nil {
@@ -87,9 +680,40 @@ func (s *Storage) GetAllListings() ([]*models.Listing, error) {
 		return nil, err
 	}
 
+	hidden, err := s.getHidden(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	following := make(map[string]bool)
+
 	var listings []*models.Listing
-	for _, userListing := range listingsMap {
+	for ownerID, userListing := range listingsMap {
+		if hidden[ownerID] {
+			continue
+		}
 		for _, listing := range userListing {
+			if listing.ID == uuid.Nil || listing.Visibility == models.Private || listing.HiddenFrom(userID) {
+				continue
+			}
+
+			if listing.Visibility == models.Followers && listing.UserID != userID {
+				isFollower, ok := following[ownerID]
+				if !ok {
+					var err error
+					isFollower, err = s.IsFollower(ownerID, userID)
+					if err != nil {
+						return nil, err
+					}
+					following[ownerID] = isFollower
+				}
+				if !isFollower {
+					continue
+				}
+			} else if !listing.VisibleTo(userID) {
+				continue
+			}
+
 			listings = append(listings, listing)
 		}
 	}
@@ -97,82 +721,1049 @@ func (s *Storage) GetAllListings() ([]*models.Listing, error) {
 	return listings, nil
 }
 
-func (s *Storage) DeleteUser(emailHash string) error {
-	s.DeleteAllUserListings(emailHash)
-	return s.NewRef("users").Child(emailHash).Delete(context.Background())
+// IsFollower reports whether user followerID follows user userID.
+func (s *Storage) IsFollower(userID string, followerID string) (bool, error) {
+	var following bool
+	if err := s.NewRef("followers").Child(userID).Child(followerID).Get(context.Background(), &following); err != nil {
+		return false, err
+	}
+	return following, nil
 }
 
-func (s *Storage) DeleteAllUserListings(emailHash string) error {
-	return s.NewRef("listings").Child(emailHash).Delete(context.Background())
+// Follow makes user followerID follow user userID. Both
+// directions of the edge are written in one multi-path update.
+func (s *Storage) Follow(followerID string, userID string) error {
+	if followerID == userID {
+		return ErrCannotFollowSelf
+	}
+	if _, err := s.GetUser(userID); err != nil {
+		return err
+	}
+
+	return s.NewRef("/").Update(context.Background(), map[string]interface{}{
+		"followers/" + userID + "/" + followerID: true,
+		"following/" + followerID + "/" + userID: true,
+	})
 }
 
-func (s *Storage) LikeListing(listingID string, listingEmail string, email string) error {
-	var listing models.Listing
+func (s *Storage) Unfollow(followerID string, userID string) error {
+	return s.NewRef("/").Update(context.Background(), map[string]interface{}{
+		"followers/" + userID + "/" + followerID: nil,
+		"following/" + followerID + "/" + userID: nil,
+	})
+}
 
-	if err := s.NewRef("listings").Child(listingEmail).Child(listingID).Get(context.Background(), &listing); err != nil {
-		return err
-	}
+// GetFollowers returns the sorted IDs of the users following userID.
+func (s *Storage) GetFollowers(userID string) ([]string, error) {
+	return s.getEdges("followers", userID)
+}
 
-	existingIndex := -1
+// GetFollowing returns the sorted IDs of the users userID follows.
+func (s *Storage) GetFollowing(userID string) ([]string, error) {
+	return s.getEdges("following", userID)
+}
 
-	for index, like := range listing.Likes {
-		if like.Email == email {
-			existingIndex = index
-			break
-		}
+func (s *Storage) getEdges(path string, userID string) ([]string, error) {
+	var edges map[string]bool
+	if err := s.NewRef(path).Child(userID).GetShallow(context.Background(), &edges); err != nil {
+		return nil, err
 	}
 
-	if existingIndex != -1 {
-		listing.Likes = append(listing.Likes[:existingIndex], listing.Likes[existingIndex+1:]...)
-	} else {
-		listing.Likes = append(listing.Likes, models.Like{Email: email})
+	ids := make([]string, 0, len(edges))
+	for id := range edges {
+		ids = append(ids, id)
 	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Block makes user userID block user targetID. Listings of blocked users are hidden
+// from the blocker, and blocked users cannot react to or comment on the blocker's
+// listings.
+func (s *Storage) Block(userID string, targetID string) error {
+	return s.setRelation("blocks", userID, targetID, true)
+}
 
-	return s.NewRef("listings").Child(listingEmail).Child(listingID).Set(context.Background(), listing)
+func (s *Storage) Unblock(userID string, targetID string) error {
+	return s.setRelation("blocks", userID, targetID, false)
+}
 
+// Mute makes user userID mute user targetID. Listings of muted users are hidden from
+// the user muting them, but muted users can still interact with theirs.
+func (s *Storage) Mute(userID string, targetID string) error {
+	return s.setRelation("mutes", userID, targetID, true)
 }
 
-func (s *Storage) CommentListing(listingID string, listingEmail string, comment models.Comment) error {
-	var listing models.Listing
+func (s *Storage) Unmute(userID string, targetID string) error {
+	return s.setRelation("mutes", userID, targetID, false)
+}
 
-	if err := s.NewRef("listings").Child(listingEmail).Child(listingID).Get(context.Background(), &listing); err != nil {
+func (s *Storage) setRelation(path string, userID string, targetID string, set bool) error {
+	if userID == targetID {
+		return ErrCannotTargetSelf
+	}
+	if !set {
+		return s.NewRef(path).Child(userID).Child(targetID).Delete(context.Background())
+	}
+	if _, err := s.GetUser(targetID); err != nil {
 		return err
 	}
+	return s.NewRef(path).Child(userID).Child(targetID).Set(context.Background(), true)
+}
 
-	listing.Comments = append(listing.Comments, models.Comment{Email: comment.Email, Comment: comment.Comment, CreatedAt: comment.CreatedAt})
+// GetBlocked returns the sorted IDs of the users userID has blocked.
+func (s *Storage) GetBlocked(userID string) ([]string, error) {
+	return s.getEdges("blocks", userID)
+}
 
-	return s.NewRef("listings").Child(listingEmail).Child(listingID).Set(context.Background(), listing)
+// GetMuted returns the sorted IDs of the users userID has muted.
+func (s *Storage) GetMuted(userID string) ([]string, error) {
+	return s.getEdges("mutes", userID)
+}
 
+// IsBlocked reports whether user userID has blocked user targetID.
+func (s *Storage) IsBlocked(userID string, targetID string) (bool, error) {
+	var blocked bool
+	if err := s.NewRef("blocks").Child(userID).Child(targetID).Get(context.Background(), &blocked); err != nil {
+		return false, err
+	}
+	return blocked, nil
 }
 
-func (s *Storage) UploadImage(listingID string, image []byte) (string, error) {
-	imagePath := "listings/" + listingID + ".jpg"
-	bucket, err := s.Storage.DefaultBucket()
-	if err != nil {
-		return "", err
+// getHidden returns the users whose listings are hidden from user userID because
+// they blocked or muted them.
+func (s *Storage) getHidden(userID string) (map[string]bool, error) {
+	hidden := make(map[string]bool)
+	for _, edges := range []func(string) ([]string, error){s.GetBlocked, s.GetMuted} {
+		ids, err := edges(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			hidden[id] = true
+		}
 	}
-	imageRef := bucket.Object(imagePath)
-	writer := imageRef.NewWriter(context.Background())
-	writer.ContentType = "image/jpeg"
-	if _, err := writer.Write(image); err != nil {
-		return "", err
+	return hidden, nil
+}
+
+// checkNotBlocked fails with ErrBlocked when the owner of listing has blocked user userID.
+func (s *Storage) checkNotBlocked(listing *models.Listing, userID string) error {
+	blocked, err := s.IsBlocked(listing.UserID, userID)
+	if err != nil {
+		return err
 	}
-	if err := writer.Close(); err != nil {
-		return "", err
+	if blocked {
+		return ErrBlocked
 	}
+	return nil
+}
 
-	lowerImagePath := strings.ToLower(imagePath)
-	lowerImagePath = strings.ReplaceAll(lowerImagePath, "/", "%2f")
+// GetSharedUserListings returns the listings of user ownerID that are
+// shared with the user with the given ID. Private listings are never included,
+// even for their owner.
+func (s *Storage) GetSharedUserListings(ownerID string, userID string) ([]*models.Listing, error) {
+	listings, err := s.GetAllUserListings(ownerID)
+	if err != nil {
+		return nil, err
+	}
 
-	return "https://firebasestorage.googleapis.com/v0/b/foodlog-9c3fd.appspot.com/o/" + lowerImagePath + "?alt=media", nil
+	var isFollower *bool
+	var shared []*models.Listing
+	for _, listing := range listings {
+		if listing.HiddenFrom(userID) {
+			continue
+		}
+		switch listing.Visibility {
+		case models.Private:
+			continue
+		case models.Followers:
+			if listing.UserID == userID {
+				break
+			}
+			if isFollower == nil {
+				following, err := s.IsFollower(ownerID, userID)
+				if err != nil {
+					return nil, err
+				}
+				isFollower = &following
+			}
+			if !*isFollower {
+				continue
+			}
+		default:
+			if !listing.VisibleTo(userID) {
+				continue
+			}
+		}
+		shared = append(shared, listing)
+	}
+	return shared, nil
 }
 
-func (s *Storage) DeleteImage(listingID string) error {
-	imagePath := "listings/" + listingID + ".jpg"
-	bucket, err := s.Storage.DefaultBucket()
+// GetFeed returns the listings the user with the given ID can see from the users
+// they follow. It fans out over the per-user listings of each followed user.
+func (s *Storage) GetFeed(userID string) ([]*models.Listing, error) {
+	following, err := s.GetFollowing(userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	imageRef := bucket.Object(imagePath)
-	return imageRef.Delete(context.Background())
+
+	hidden, err := s.getHidden(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed []*models.Listing
+	for _, ownerID := range following {
+		if hidden[ownerID] {
+			continue
+		}
+		listings, err := s.GetSharedUserListings(ownerID, userID)
+		if err != nil {
+			return nil, err
+		}
+		feed = append(feed, listings...)
+	}
+	return feed, nil
+}
+
+// CanView reports whether the user with the given ID may see the listing.
+func (s *Storage) CanView(listing *models.Listing, userID string) (bool, error) {
+	if listing.HiddenFrom(userID) {
+		return false, nil
+	}
+	if listing.Visibility == models.Followers && listing.UserID != userID {
+		return s.IsFollower(listing.UserID, userID)
+	}
+	return listing.VisibleTo(userID), nil
+}
+
+// DeleteUserAs deletes user userID on behalf of user callerID, who must be that user
+// or an admin.
+func (s *Storage) DeleteUserAs(callerID string, userID string) error {
+	if callerID != userID {
+		admin, err := s.IsAdmin(callerID)
+		if err != nil {
+			return err
+		}
+		if !admin {
+			return ErrNotAccountOwner
+		}
+	}
+	return s.DeleteUser(userID)
+}
+
+func (s *Storage) DeleteUser(userID string) error {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	s.DeleteAllUserListings(userID)
+	if err := s.deleteFollowEdges(userID); err != nil {
+		return err
+	}
+	updates := map[string]interface{}{
+		"users/" + userID:                         nil,
+		"emails/" + util.Base64Encode(user.Email): nil,
+		"blocks/" + userID:                        nil,
+		"mutes/" + userID:                         nil,
+	}
+	if user.Handle != "" {
+		updates["handles/"+user.Handle] = nil
+	}
+	return s.NewRef("/").Update(context.Background(), updates)
+}
+
+// deleteFollowEdges removes every follow edge to or from user userID.
+func (s *Storage) deleteFollowEdges(userID string) error {
+	followers, err := s.GetFollowers(userID)
+	if err != nil {
+		return err
+	}
+	following, err := s.GetFollowing(userID)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"followers/" + userID: nil,
+		"following/" + userID: nil,
+	}
+	for _, id := range followers {
+		updates["following/"+id+"/"+userID] = nil
+	}
+	for _, id := range following {
+		updates["followers/"+id+"/"+userID] = nil
+	}
+	return s.NewRef("/").Update(context.Background(), updates)
+}
+
+func (s *Storage) DeleteAllUserListings(userID string) error {
+	var listingIDs map[string]bool
+	if err := s.NewRef("listings").Child(userID).GetShallow(context.Background(), &listingIDs); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"listings/" + userID: nil,
+	}
+	for id := range listingIDs {
+		updates["listing-index/"+id] = nil
+	}
+	return s.NewRef("/").Update(context.Background(), updates)
+}
+
+// incrementVersion is a server value that atomically bumps a listing's version, so
+// likes and comments invalidate cached copies without rewriting the whole listing.
+var incrementVersion = map[string]interface{}{".sv": map[string]interface{}{"increment": 1}}
+
+// getVisibleListing loads a listing by id and returns it with its database reference,
+// failing with ErrListingNotVisible when the user with the given ID may not see it.
+func (s *Storage) getVisibleListing(listingID string, userID string) (*db.Ref, *models.Listing, error) {
+	ownerID, err := s.GetListingOwner(listingID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ref := s.NewRef("listings").Child(ownerID).Child(listingID)
+
+	var listing models.Listing
+	if err := ref.Get(context.Background(), &listing); err != nil {
+		return nil, nil, err
+	}
+	if listing.ID == uuid.Nil {
+		return nil, nil, ErrListingNotFound
+	}
+
+	visible, err := s.CanView(&listing, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !visible {
+		return nil, nil, ErrListingNotVisible
+	}
+	return ref, &listing, nil
+}
+
+// errUnchanged aborts a transaction whose write would not change anything.
+var errUnchanged = errors.New("unchanged")
+
+// SetReaction sets the reaction of the user with the given ID, or clears it when
+// reaction is empty. It is idempotent: setting the current reaction again changes
+// nothing. Only that user's entry under reactions is touched, inside a transaction,
+// so concurrent reactions from different users never overwrite each other.
+func (s *Storage) SetReaction(listingID string, userID string, reaction models.ReactionType) error {
+	return s.updateReaction(listingID, userID, func(current models.ReactionType) models.ReactionType {
+		return reaction
+	})
+}
+
+// SetLike records or removes the like of the user with the given ID. Likes are
+// like reactions, so liking replaces any other reaction and unliking leaves a
+// different reaction in place.
+func (s *Storage) SetLike(listingID string, userID string, liked bool) error {
+	return s.updateReaction(listingID, userID, func(current models.ReactionType) models.ReactionType {
+		if liked {
+			return models.ReactionLike
+		}
+		if current == models.ReactionLike {
+			return ""
+		}
+		return current
+	})
+}
+
+func (s *Storage) updateReaction(listingID string, userID string, next func(models.ReactionType) models.ReactionType) error {
+	ref, listing, err := s.getVisibleListing(listingID, userID)
+	if err != nil {
+		return err
+	}
+	// Blocked users may still take back a reaction they left before the block.
+	blocked := s.checkNotBlocked(listing, userID)
+	if blocked != nil && !errors.Is(blocked, ErrBlocked) {
+		return blocked
+	}
+
+	if listing.HasLegacyLikes() {
+		if err := s.migrateLegacyLikes(ref, listing); err != nil {
+			return err
+		}
+	}
+
+	err = ref.Child("reactions").Child(userID).Transaction(context.Background(), func(node db.TransactionNode) (interface{}, error) {
+		var current models.Reaction
+		if err := node.Unmarshal(&current); err != nil {
+			return nil, err
+		}
+
+		reaction := next(current.Type)
+		if reaction == current.Type {
+			return nil, errUnchanged
+		}
+		if reaction == "" {
+			return nil, nil
+		}
+		if blocked != nil {
+			return nil, blocked
+		}
+		return models.Reaction{UserID: userID, Type: reaction, CreatedAt: time.Now()}, nil
+	})
+	if errors.Is(err, errUnchanged) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return ref.Child("version").Set(context.Background(), incrementVersion)
+}
+
+// migrateLegacyLikes moves likes stored before reactions existed into like reactions.
+// The listing was decoded with those likes already folded into its reactions.
+func (s *Storage) migrateLegacyLikes(ref *db.Ref, listing *models.Listing) error {
+	updates := map[string]interface{}{
+		"likes": nil,
+	}
+	for key, reaction := range listing.Reactions {
+		updates["reactions/"+key] = reaction
+	}
+	return ref.Update(context.Background(), updates)
+}
+
+// MigrateLikesToReactions moves the legacy likes of every stored listing into like
+// reactions.
+func (s *Storage) MigrateLikesToReactions() (int, error) {
+	var listingsMap map[string]map[string]*models.Listing
+	if err := s.NewRef("listings").Get(context.Background(), &listingsMap); err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for userID, userListings := range listingsMap {
+		for id, listing := range userListings {
+			if !listing.HasLegacyLikes() {
+				continue
+			}
+			if err := s.migrateLegacyLikes(s.NewRef("listings").Child(userID).Child(id), listing); err != nil {
+				return migrated, err
+			}
+			migrated++
+		}
+	}
+	return migrated, nil
+}
+
+// GetLikes returns the likes of a listing, newest first.
+func (s *Storage) GetLikes(listingID string, userID string) ([]models.Like, error) {
+	_, listing, err := s.getVisibleListing(listingID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var likes []models.Like
+	for _, reaction := range listing.Reactions {
+		if reaction.Type == models.ReactionLike {
+			likes = append(likes, models.Like{UserID: reaction.UserID, CreatedAt: reaction.CreatedAt})
+		}
+	}
+
+	sort.Slice(likes, func(i, j int) bool {
+		return likes[i].CreatedAt.After(likes[j].CreatedAt)
+	})
+	return likes, nil
+}
+
+// CommentListing appends a comment by the user with the given ID under a new id in
+// a single multi-path update, so concurrent comments never overwrite each other or the
+// rest of the listing. The author and timestamp always come from the server. Replies
+// may only be made to top-level comments.
+func (s *Storage) CommentListing(listingID string, userID string, comment models.Comment) (*models.Comment, error) {
+	if err := comment.Validate(); err != nil {
+		return nil, err
+	}
+
+	_, listing, err := s.getVisibleListing(listingID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNotBlocked(listing, userID); err != nil {
+		return nil, err
+	}
+
+	if comment.ParentID != "" {
+		parent, ok := listing.Comments[comment.ParentID]
+		if !ok || parent.ParentID != "" {
+			return nil, models.ErrInvalidComment
+		}
+	}
+
+	author, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	created := models.Comment{
+		ID:        uuid.New().String(),
+		ParentID:  comment.ParentID,
+		UserID:    userID,
+		Name:      author.Name,
+		Comment:   strings.TrimSpace(comment.Comment),
+		CreatedAt: time.Now(),
+	}
+
+	flagged := GetContentFilter().Run(&filter.Content{
+		UserID:    userID,
+		Kind:      models.KindComment,
+		Text:      created.Comment,
+		CreatedAt: created.CreatedAt,
+	})
+	if flagged != nil && flagged.Action == filter.Reject {
+		return nil, fmt.Errorf("%w: %s", filter.ErrRejected, flagged.Rule)
+	}
+
+	path := listingPath(listing)
+	err = s.NewRef("/").Update(context.Background(), map[string]interface{}{
+		path + "/comments/" + created.ID: created,
+		path + "/version":                incrementVersion,
+		"comment-index/" + created.ID:    listingID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if flagged != nil {
+		if err := s.flagContent(models.KindComment, created.ID, listingID, flagged); err != nil {
+			return nil, err
+		}
+	}
+	return &created, nil
+}
+
+// GetComments returns the comments of a listing as threads, oldest first.
+func (s *Storage) GetComments(listingID string, userID string) ([]models.Comment, error) {
+	_, listing, err := s.getVisibleListing(listingID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return listing.Thread(), nil
+}
+
+// UpdateComment replaces the text of a comment. Only its author or the owner of the
+// listing may edit it.
+func (s *Storage) UpdateComment(listingID string, commentID string, userID string, text string) (*models.Comment, error) {
+	edit := models.Comment{Comment: text}
+	if err := edit.Validate(); err != nil {
+		return nil, err
+	}
+
+	ref, listing, err := s.getVisibleListing(listingID, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Blocked users may still delete their comments, but not change them.
+	if err := s.checkNotBlocked(listing, userID); err != nil {
+		return nil, err
+	}
+
+	current, ok := listing.Comments[commentID]
+	if !ok || current.Comment == "" {
+		return nil, ErrCommentNotFound
+	}
+	if current.UserID != userID && listing.UserID != userID {
+		return nil, ErrCommentNotEditable
+	}
+
+	// Only changed text is screened, so saving a comment as it is does not count as
+	// posting it again.
+	var flagged *filter.Result
+	if current.Comment != strings.TrimSpace(text) {
+		flagged = GetContentFilter().Run(&filter.Content{
+			UserID: userID,
+			Kind:   models.KindComment,
+			Text:   strings.TrimSpace(text),
+		})
+		if flagged != nil && flagged.Action == filter.Reject {
+			return nil, fmt.Errorf("%w: %s", filter.ErrRejected, flagged.Rule)
+		}
+	}
+
+	var updated models.Comment
+	err = ref.Child("comments").Child(commentID).Transaction(context.Background(), func(node db.TransactionNode) (interface{}, error) {
+		updated = models.Comment{}
+		if err := node.Unmarshal(&updated); err != nil {
+			return nil, err
+		}
+		if updated.Comment == "" {
+			return nil, ErrCommentNotFound
+		}
+		if updated.UserID != userID && listing.UserID != userID {
+			return nil, ErrCommentNotEditable
+		}
+
+		updated.ID = commentID
+		updated.Comment = strings.TrimSpace(text)
+		updated.UpdatedAt = time.Now()
+		return &updated, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ref.Child("version").Set(context.Background(), incrementVersion); err != nil {
+		return nil, err
+	}
+
+	if flagged != nil {
+		if err := s.flagContent(models.KindComment, commentID, listingID, flagged); err != nil {
+			return nil, err
+		}
+	}
+	return &updated, nil
+}
+
+// DeleteComment removes a comment together with its replies. Only its author or the
+// owner of the listing may delete it.
+func (s *Storage) DeleteComment(listingID string, commentID string, userID string) error {
+	_, listing, err := s.getVisibleListing(listingID, userID)
+	if err != nil {
+		return err
+	}
+
+	comment, ok := listing.Comments[commentID]
+	if !ok {
+		return ErrCommentNotFound
+	}
+	if comment.UserID != userID && listing.UserID != userID {
+		return ErrCommentNotEditable
+	}
+
+	return s.NewRef("/").Update(context.Background(), deleteCommentUpdates(listing, commentID))
+}
+
+// listingPath is the database path of a stored listing.
+func listingPath(listing *models.Listing) string {
+	return "listings/" + listing.UserID + "/" + listing.ID.String()
+}
+
+// deleteCommentUpdates returns the multi-path updates, relative to the database root,
+// that delete a comment of listing together with its replies and their index entries.
+func deleteCommentUpdates(listing *models.Listing, commentID string) map[string]interface{} {
+	path := listingPath(listing)
+	updates := map[string]interface{}{
+		path + "/comments/" + commentID: nil,
+		path + "/version":               incrementVersion,
+		"comment-index/" + commentID:    nil,
+	}
+	for key, reply := range listing.Comments {
+		if reply.ParentID == commentID {
+			updates[path+"/comments/"+key] = nil
+			updates["comment-index/"+key] = nil
+		}
+	}
+	return updates
+}
+
+// ReportListing records the report of user userID against a listing they can see.
+// Each user has at most one open report per listing; reporting it again replaces it.
+func (s *Storage) ReportListing(listingID string, userID string, report models.Report) error {
+	if err := report.Validate(); err != nil {
+		return err
+	}
+	if _, _, err := s.getVisibleListing(listingID, userID); err != nil {
+		return err
+	}
+	return s.saveReport(models.KindListing, listingID, listingID, userID, report)
+}
+
+// ReportComment records the report of user userID against a comment on a listing they
+// can see. Comments stored before they had IDs cannot be reported.
+func (s *Storage) ReportComment(commentID string, userID string, report models.Report) error {
+	if err := report.Validate(); err != nil {
+		return err
+	}
+
+	listingID, err := s.getCommentListing(commentID)
+	if err != nil {
+		return err
+	}
+	_, listing, err := s.getVisibleListing(listingID, userID)
+	if err != nil {
+		return err
+	}
+	if comment, ok := listing.Comments[commentID]; !ok || comment.Hidden {
+		return ErrCommentNotFound
+	}
+	return s.saveReport(models.KindComment, commentID, listingID, userID, report)
+}
+
+func (s *Storage) saveReport(kind models.ContentKind, id string, listingID string, userID string, report models.Report) error {
+	report.UserID = userID
+	report.CreatedAt = time.Now()
+
+	path := "reports/" + string(kind) + "/" + id
+	return s.NewRef("/").Update(context.Background(), map[string]interface{}{
+		path + "/kind":              kind,
+		path + "/id":                id,
+		path + "/listing_id":        listingID,
+		path + "/reports/" + userID: report,
+	})
+}
+
+// filterReporter is the reporter ID of reports filed by the content filter.
+const filterReporter = "filter"
+
+// flagContent reports content the content filter flagged to the moderation queue.
+func (s *Storage) flagContent(kind models.ContentKind, id string, listingID string, flagged *filter.Result) error {
+	return s.saveReport(kind, id, listingID, filterReporter, models.Report{
+		Reason: flagged.Reason,
+		Note:   "Flagged by " + flagged.Rule,
+	})
+}
+
+// getCommentListing resolves the id of the listing a comment belongs to.
+func (s *Storage) getCommentListing(commentID string) (string, error) {
+	var listingID string
+	if err := s.NewRef("comment-index").Child(commentID).Get(context.Background(), &listingID); err != nil {
+		return "", err
+	}
+	if listingID == "" {
+		return "", ErrCommentNotFound
+	}
+	return listingID, nil
+}
+
+// IsModerator reports whether user userID is a moderator. Moderators are granted by
+// setting moderators/<user id> to true in the database.
+func (s *Storage) IsModerator(userID string) (bool, error) {
+	var moderator bool
+	if err := s.NewRef("moderators").Child(userID).Get(context.Background(), &moderator); err != nil {
+		return false, err
+	}
+	return moderator, nil
+}
+
+// SetModerator grants or revokes the moderator role of user userID.
+func (s *Storage) SetModerator(userID string, moderator bool) error {
+	if !moderator {
+		return s.NewRef("moderators").Child(userID).Delete(context.Background())
+	}
+	return s.NewRef("moderators").Child(userID).Set(context.Background(), true)
+}
+
+func (s *Storage) checkModerator(userID string) error {
+	moderator, err := s.IsModerator(userID)
+	if err != nil {
+		return err
+	}
+	if !moderator {
+		return ErrNotModerator
+	}
+	return nil
+}
+
+// GetModerationQueue returns the reported listings and comments, most reported first.
+// Reports of content that no longer exists are dropped on the way.
+func (s *Storage) GetModerationQueue(moderatorID string) ([]models.ModerationItem, error) {
+	if err := s.checkModerator(moderatorID); err != nil {
+		return nil, err
+	}
+
+	var reported map[models.ContentKind]map[string]*models.ReportedItem
+	if err := s.NewRef("reports").Get(context.Background(), &reported); err != nil {
+		return nil, err
+	}
+
+	stale := map[string]interface{}{}
+	queue := []models.ModerationItem{}
+	for kind, items := range reported {
+		for id, item := range items {
+			item.Kind, item.ID = kind, id
+
+			listing, err := s.GetListingByID(item.ListingID)
+			if errors.Is(err, ErrListingNotFound) {
+				stale["reports/"+string(kind)+"/"+id] = nil
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			summary := models.NewModerationItem(item)
+			switch kind {
+			case models.KindListing:
+				summary.Preview = listing.Title
+				summary.Hidden = listing.Hidden
+			case models.KindComment:
+				comment, ok := listing.Comments[id]
+				if !ok {
+					stale["reports/"+string(kind)+"/"+id] = nil
+					continue
+				}
+				summary.Preview = comment.Comment
+				summary.Hidden = comment.Hidden
+			}
+			queue = append(queue, summary)
+		}
+	}
+
+	if len(stale) > 0 {
+		if err := s.NewRef("/").Update(context.Background(), stale); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(queue, func(i, j int) bool {
+		if queue[i].ReportCount != queue[j].ReportCount {
+			return queue[i].ReportCount > queue[j].ReportCount
+		}
+		return queue[i].LastReportedAt.After(queue[j].LastReportedAt)
+	})
+	return queue, nil
+}
+
+// Moderate hides, restores or deletes a listing or comment on behalf of moderator
+// moderatorID. The open reports of the content are resolved and the action is written
+// to the audit log in the same multi-path update.
+func (s *Storage) Moderate(moderatorID string, kind models.ContentKind, id string, action models.ModerationAction, note string) (*models.AuditEntry, error) {
+	if !action.Valid() {
+		return nil, models.ErrInvalidAction
+	}
+	if err := s.checkModerator(moderatorID); err != nil {
+		return nil, err
+	}
+
+	listingID := id
+	if kind == models.KindComment {
+		var err error
+		if listingID, err = s.getCommentListing(id); err != nil {
+			return nil, err
+		}
+	}
+
+	listing, err := s.GetListingByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := listing.Comments[id]; kind == models.KindComment && !ok {
+		return nil, ErrCommentNotFound
+	}
+
+	reportPath := "reports/" + string(kind) + "/" + id
+	var reports map[string]bool
+	if err := s.NewRef(reportPath).Child("reports").GetShallow(context.Background(), &reports); err != nil {
+		return nil, err
+	}
+
+	entry := models.AuditEntry{
+		ID:          uuid.New().String(),
+		ModeratorID: moderatorID,
+		Action:      action,
+		Kind:        kind,
+		TargetID:    id,
+		ListingID:   listingID,
+		OwnerID:     listing.UserID,
+		ReportCount: len(reports),
+		Note:        note,
+		CreatedAt:   time.Now(),
+	}
+
+	path := listingPath(listing)
+	updates := map[string]interface{}{}
+	switch {
+	case kind == models.KindListing && action == models.ActionDelete:
+		updates = deleteListingUpdates(listing)
+	case kind == models.KindComment && action == models.ActionDelete:
+		updates = deleteCommentUpdates(listing, id)
+	default:
+		var hidden interface{}
+		if action == models.ActionHide {
+			hidden = true
+		}
+		if kind == models.KindListing {
+			updates[path+"/hidden"] = hidden
+		} else {
+			updates[path+"/comments/"+id+"/hidden"] = hidden
+		}
+		updates[path+"/version"] = incrementVersion
+	}
+	updates[reportPath] = nil
+	updates["moderation-log/"+entry.ID] = entry
+
+	if err := s.NewRef("/").Update(context.Background(), updates); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetAuditLog returns the moderation actions taken so far, newest first.
+func (s *Storage) GetAuditLog(moderatorID string) ([]models.AuditEntry, error) {
+	if err := s.checkModerator(moderatorID); err != nil {
+		return nil, err
+	}
+
+	var entries map[string]models.AuditEntry
+	if err := s.NewRef("moderation-log").Get(context.Background(), &entries); err != nil {
+		return nil, err
+	}
+
+	audit := make([]models.AuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		audit = append(audit, entry)
+	}
+	sort.Slice(audit, func(i, j int) bool {
+		return audit[i].CreatedAt.After(audit[j].CreatedAt)
+	})
+	return audit, nil
+}
+
+// IsAdmin reports whether user userID is an admin. Admins are granted by setting
+// admins/<user id> to true in the database.
+func (s *Storage) IsAdmin(userID string) (bool, error) {
+	var admin bool
+	if err := s.NewRef("admins").Child(userID).Get(context.Background(), &admin); err != nil {
+		return false, err
+	}
+	return admin, nil
+}
+
+func (s *Storage) checkAdmin(userID string) error {
+	admin, err := s.IsAdmin(userID)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return ErrNotAdmin
+	}
+	return nil
+}
+
+// SetAdmin grants or revokes the admin role of user userID.
+func (s *Storage) SetAdmin(userID string, admin bool) error {
+	if !admin {
+		return s.NewRef("admins").Child(userID).Delete(context.Background())
+	}
+	return s.NewRef("admins").Child(userID).Set(context.Background(), true)
+}
+
+// GetProduct returns the packaged product with the given EAN-13 barcode.
+func (s *Storage) GetProduct(barcode string) (*foods.Product, error) {
+	var product *foods.Product
+	if err := s.NewRef("products").Child(barcode).Get(context.Background(), &product); err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, foods.ErrProductNotFound
+	}
+	return product, nil
+}
+
+// ImportProducts adds products to the product table, replacing products with the
+// same barcode. Only admins may import products.
+func (s *Storage) ImportProducts(adminID string, products []foods.Product) error {
+	if err := s.checkAdmin(adminID); err != nil {
+		return err
+	}
+
+	updates := make(map[string]interface{}, len(products))
+	for _, product := range products {
+		updates[product.Barcode] = product
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return s.NewRef("products").Update(context.Background(), updates)
+}
+
+// ImagePath is the path listing images are served from, behind the visibility check
+// of GetImage.
+func ImagePath(listingID string) string {
+	return "/listings/" + listingID + "/image"
+}
+
+// checkListingOwner returns ErrNotListingOwner unless user userID owns the listing.
+func (s *Storage) checkListingOwner(userID string, listingID string) error {
+	ownerID, err := s.GetListingOwner(listingID)
+	if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return ErrNotListingOwner
+	}
+	return nil
+}
+
+// UploadImage stores the image of a listing owned by user userID and points the
+// listing at it, returning the path the image is served from.
+func (s *Storage) UploadImage(userID string, listingID string, image []byte) (string, error) {
+	if err := s.checkListingOwner(userID, listingID); err != nil {
+		return "", err
+	}
+
+	imagePath := "listings/" + listingID + ".jpg"
+	bucket, err := s.Storage.DefaultBucket()
+	if err != nil {
+		return "", err
+	}
+	imageRef := bucket.Object(imagePath)
+	writer := imageRef.NewWriter(context.Background())
+	writer.ContentType = "image/jpeg"
+	if _, err := writer.Write(image); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	path := ImagePath(listingID)
+	_, err = s.UpdateListing(userID, listingID, anyVersion, func(listing *models.Listing) error {
+		listing.Image = path
+		return nil
+	})
+	return path, err
+}
+
+func (s *Storage) GetImage(listingID string) ([]byte, error) {
+	imagePath := "listings/" + listingID + ".jpg"
+	bucket, err := s.Storage.DefaultBucket()
+	if err != nil {
+		return nil, err
+	}
+	reader, err := bucket.Object(imagePath).NewReader(context.Background())
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// DeleteImage removes the image of a listing owned by user userID.
+func (s *Storage) DeleteImage(userID string, listingID string) error {
+	if err := s.checkListingOwner(userID, listingID); err != nil {
+		return err
+	}
+
+	imagePath := "listings/" + listingID + ".jpg"
+	bucket, err := s.Storage.DefaultBucket()
+	if err != nil {
+		return err
+	}
+	err = bucket.Object(imagePath).Delete(context.Background())
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return ErrImageNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.UpdateListing(userID, listingID, anyVersion, func(listing *models.Listing) error {
+		if listing.Image == ImagePath(listingID) {
+			listing.Image = ""
+		}
+		return nil
+	})
+	return err
 }
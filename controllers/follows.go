@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/Ygnas/FoodLog/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+func FollowUser(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	handle := chi.URLParam(r, "handle")
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	user, err := storage.GetUserByHandle(handle)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = storage.Follow(claims["id"].(string), user.ID.String())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Write([]byte("User followed"))
+}
+
+func UnfollowUser(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	handle := chi.URLParam(r, "handle")
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	user, err := storage.GetUserByHandle(handle)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = storage.Unfollow(claims["id"].(string), user.ID.String())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Write([]byte("User unfollowed"))
+}
+
+func GetFollowers(w http.ResponseWriter, r *http.Request) {
+	listFollows(w, r, (*Storage).GetFollowers)
+}
+
+func GetFollowing(w http.ResponseWriter, r *http.Request) {
+	listFollows(w, r, (*Storage).GetFollowing)
+}
+
+func listFollows(w http.ResponseWriter, r *http.Request, edges func(*Storage, string) ([]string, error)) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	limit, offset, ok := parsePage(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	handle := chi.URLParam(r, "handle")
+
+	storage := NewStorage()
+	user, err := storage.GetUserByHandle(handle)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ids, err := edges(storage, user.ID.String())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeUserPage(w, storage, ids, limit, offset)
+}
+
+// writeUserPage writes a page of summaries of the users with the given IDs, skipping
+// users that no longer exist.
+func writeUserPage(w http.ResponseWriter, storage *Storage, ids []string, limit int, offset int) {
+	idPage := models.NewPage(ids, limit, offset)
+	page := models.Page[models.UserSummary]{Items: []models.UserSummary{}, Total: idPage.Total, Limit: limit, Offset: offset}
+	for _, id := range idPage.Items {
+		user, err := storage.GetUser(id)
+		if errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		page.Items = append(page.Items, models.UserSummary{ID: user.ID.String(), Handle: user.Handle, Name: user.Name})
+	}
+
+	responseJSON, err := json.Marshal(page)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
+
+func GetFeed(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	limit, offset, ok := parsePage(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	query, ok := parseListingQuery(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	listings, err := storage.GetFeed(claims["id"].(string))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	listings = query.Filter(listings)
+
+	sort.Slice(listings, func(i, j int) bool {
+		return listings[i].CreatedAt.After(listings[j].CreatedAt)
+	})
+
+	page := models.NewPage(listings, limit, offset)
+	for _, listing := range page.Items {
+		listing.Summarize(claims["id"].(string))
+	}
+
+	responseJSON, err := json.Marshal(page)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeWithETag(w, r, bodyETag(responseJSON), responseJSON)
+}
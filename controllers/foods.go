@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Ygnas/FoodLog/foods"
+	"github.com/Ygnas/FoodLog/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+var foodDatabase *foods.Database
+
+// NewFoodDatabase loads the food database from the CSV or JSON file named by
+// FOODS_FILE, or from the dataset bundled with the server.
+func NewFoodDatabase() *foods.Database {
+	if path := os.Getenv("FOODS_FILE"); path != "" {
+		database, err := foods.Load(path)
+		if err == nil {
+			foodDatabase = database
+			return database
+		}
+		log.Printf("Could not load food database, using bundled foods: %v\n", err)
+	}
+
+	database, err := foods.Bundled()
+	if err != nil {
+		log.Printf("Could not load bundled foods: %v\n", err)
+	}
+	foodDatabase = database
+	return database
+}
+
+func GetFoodDatabase() *foods.Database {
+	return foodDatabase
+}
+
+func SetFoodDatabase(database *foods.Database) {
+	foodDatabase = database
+}
+
+// computeNutrition replaces the nutrition of a listing with the sum of its ingredients
+// when every ingredient is linked to a food and has a known weight. Otherwise the
+// nutrition given by the user is kept.
+func computeNutrition(listing *models.Listing) error {
+	if foodDatabase == nil {
+		for _, ingredient := range listing.Ingredients {
+			if ingredient.FoodID != "" {
+				return fmt.Errorf("%w: unknown food %q", models.ErrInvalidIngredient, ingredient.FoodID)
+			}
+		}
+		return nil
+	}
+
+	nutrition, ok, err := foodDatabase.Nutrition(listing.Ingredients)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidIngredient, err)
+	}
+	if !ok {
+		return nil
+	}
+	if err := nutrition.Validate(); err != nil {
+		return err
+	}
+	listing.Nutrition = nutrition
+	return nil
+}
+
+// SearchFoods returns a page of the foods matching the q query parameter.
+func SearchFoods(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := parsePage(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	var results []foods.Food
+	if foodDatabase != nil {
+		results = foodDatabase.Search(r.URL.Query().Get("q"))
+	}
+
+	responseJSON, err := json.Marshal(models.NewPage(results, limit, offset))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
+
+func GetFood(w http.ResponseWriter, r *http.Request) {
+	if foodDatabase == nil {
+		writeError(w, foods.ErrFoodNotFound)
+		return
+	}
+
+	food, err := foodDatabase.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(food)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
+
+// maxProductFileSize is the largest product data file accepted by ImportProducts.
+const maxProductFileSize = 10 << 20
+
+// GetProductByBarcode looks up a packaged product by its EAN-13 or UPC-A barcode.
+func GetProductByBarcode(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	barcode, err := foods.NormalizeBarcode(chi.URLParam(r, "ean"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	storage := NewStorage()
+	product, err := storage.GetProduct(barcode)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(product)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
+
+// ImportProducts adds the products of a CSV or JSON data file in the request body to
+// the product table. The format is taken from the Content-Type header.
+func ImportProducts(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	var format string
+	switch contentType := r.Header.Get("Content-Type"); {
+	case strings.HasPrefix(contentType, "text/csv"):
+		format = foods.FormatCSV
+	case contentType == "" || strings.HasPrefix(contentType, "application/json"):
+		format = foods.FormatJSON
+	default:
+		http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	// Only admins get their file read at all.
+	storage := NewStorage()
+	if err := storage.checkAdmin(claims["id"].(string)); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	products, err := foods.ParseProducts(http.MaxBytesReader(w, r.Body, maxProductFileSize), format)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = storage.ImportProducts(claims["id"].(string), products)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(map[string]int{"imported": len(products)})
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"log"
+	"net/http"
 	"os"
 
 	"github.com/go-chi/jwtauth/v5"
@@ -32,3 +33,16 @@ func (j *Jwt) GetToken(claims map[string]interface{}) string {
 	_, tokenString, _ := j.TokenAuth.Encode(claims)
 	return tokenString
 }
+
+// RequireUserID rejects tokens issued before users had IDs, which carry no "id" claim.
+// Such users have to log in again.
+func RequireUserID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if id, ok := claims["id"].(string); !ok || id == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -2,9 +2,11 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/Ygnas/FoodLog/models"
@@ -25,18 +27,30 @@ func GetListing(w http.ResponseWriter, r *http.Request) {
 	_, claims, _ := jwtauth.FromContext(r.Context())
 
 	storage := NewStorage()
-	listing, err := storage.GetListing(util.Base64Encode(claims["email"].(string)), id)
+	listing, err := storage.GetListingByID(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	visible, err := storage.CanView(listing, claims["id"].(string))
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	if !visible {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	listing.Summarize(claims["id"].(string))
 
 	responseJSON, err := json.Marshal(listing)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	w.Write([]byte(responseJSON))
+	writeWithETag(w, r, listingETag(listing), responseJSON)
 }
 
 func GetAllUserListings(w http.ResponseWriter, r *http.Request) {
@@ -46,25 +60,36 @@ func GetAllUserListings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query, ok := parseListingQuery(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
 	_, claims, _ := jwtauth.FromContext(r.Context())
 
 	storage := NewStorage()
-	listings, err := storage.GetAllUserListings(util.Base64Encode(claims["email"].(string)))
+	listings, err := storage.GetAllUserListings(claims["id"].(string))
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	listings = query.Filter(listings)
 
 	sort.Slice(listings, func(i, j int) bool {
 		return listings[i].CreatedAt.After(listings[j].CreatedAt)
 	})
 
+	for _, listing := range listings {
+		listing.Summarize(claims["id"].(string))
+	}
+
 	responseJSON, err := json.Marshal(listings)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	w.Write([]byte(responseJSON))
+	writeWithETag(w, r, bodyETag(responseJSON), responseJSON)
 }
 
 func CreateListing(w http.ResponseWriter, r *http.Request) {
@@ -82,27 +107,41 @@ func CreateListing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if listing.ID == uuid.Nil {
-		listing.ID = uuid.New()
+	if listing.Visibility == "" {
+		listing.Visibility = models.Private
+	}
+	if err := listing.Validate(); err != nil {
+		writeError(w, err)
+		return
 	}
-	if listing.CreatedAt.IsZero() {
-		listing.CreatedAt = time.Now()
+
+	// IDs are always generated here so a client cannot take over the index entry of
+	// another listing or overwrite one of its own without the version checks.
+	listing.ID = uuid.New()
+	listing.CreatedAt = time.Now()
+	listing.Ingredients = models.NormalizeIngredients(listing.Ingredients)
+	if err := computeNutrition(&listing); err != nil {
+		writeError(w, err)
+		return
 	}
 
 	_, claims, _ := jwtauth.FromContext(r.Context())
 
 	storage := NewStorage()
-	err = storage.Create(util.Base64Encode(claims["email"].(string)), &listing)
+	err = storage.Create(claims["id"].(string), &listing)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
+	listing.Summarize(claims["id"].(string))
+
 	responseJSON, err := json.Marshal(listing)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("ETag", listingETag(&listing))
 	w.Write([]byte(responseJSON))
 }
 
@@ -116,10 +155,16 @@ func DeleteListing(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	_, claims, _ := jwtauth.FromContext(r.Context())
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
 	storage := NewStorage()
-	err = storage.Delete(util.Base64Encode(claims["email"].(string)), id)
+	err = storage.Delete(claims["id"].(string), id, version)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -133,29 +178,127 @@ func UpdateListing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var listing models.Listing
+	id := chi.URLParam(r, "id")
 
-	err = json.NewDecoder(r.Body).Decode(&listing)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var edit models.Listing
+
+	err = json.NewDecoder(r.Body).Decode(&edit)
 	if err != nil {
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
 
-	listing.UpdatedAt = time.Now()
+	if edit.Visibility == "" {
+		edit.Visibility = models.Private
+	}
+	if err := edit.Validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
 	_, claims, _ := jwtauth.FromContext(r.Context())
 
 	storage := NewStorage()
-	err = storage.UpdateListing(util.Base64Encode(claims["email"].(string)), &listing)
+	listing, err := storage.EditListing(claims["id"].(string), id, version, func(listing *models.Listing) error {
+		listing.ApplyEdits(&edit)
+		if err := computeNutrition(listing); err != nil {
+			return err
+		}
+		listing.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	listing.Summarize(claims["id"].(string))
+
+	responseJSON, err := json.Marshal(listing)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("ETag", listingETag(listing))
+	w.Write([]byte(responseJSON))
+}
+
+// PatchListing applies a JSON Merge Patch to the user-editable fields of a listing.
+func PatchListing(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "" && !strings.HasPrefix(contentType, "application/merge-patch+json") && !strings.HasPrefix(contentType, "application/json") {
+		http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(patch) {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	listing, err := storage.EditListing(claims["id"].(string), id, version, func(listing *models.Listing) error {
+		current, err := json.Marshal(listing)
+		if err != nil {
+			return err
+		}
+
+		patched, err := util.MergePatch(current, patch)
+		if err != nil {
+			return models.ErrInvalidListing
+		}
+
+		var edit models.Listing
+		if err := json.Unmarshal(patched, &edit); err != nil {
+			return models.ErrInvalidListing
+		}
+		if err := edit.Validate(); err != nil {
+			return err
+		}
+
+		listing.ApplyEdits(&edit)
+		if err := computeNutrition(listing); err != nil {
+			return err
+		}
+		listing.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	listing.Summarize(claims["id"].(string))
 
 	responseJSON, err := json.Marshal(listing)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("ETag", listingETag(listing))
 	w.Write([]byte(responseJSON))
 }
 
@@ -166,26 +309,47 @@ func GetAllListings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query, ok := parseListingQuery(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
 	storage := NewStorage()
-	listings, err := storage.GetAllListings()
+	listings, err := storage.GetAllListings(claims["id"].(string))
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	listings = query.Filter(listings)
 
 	sort.Slice(listings, func(i, j int) bool {
 		return listings[i].CreatedAt.After(listings[j].CreatedAt)
 	})
 
+	for _, listing := range listings {
+		listing.Summarize(claims["id"].(string))
+	}
+
 	responseJSON, err := json.Marshal(listings)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	w.Write([]byte(responseJSON))
+	writeWithETag(w, r, bodyETag(responseJSON), responseJSON)
 }
 
 func LikeListing(w http.ResponseWriter, r *http.Request) {
+	setLike(w, r, true)
+}
+
+func UnlikeListing(w http.ResponseWriter, r *http.Request) {
+	setLike(w, r, false)
+}
+
+func setLike(w http.ResponseWriter, r *http.Request, liked bool) {
 	err := GetFirebaseDatabase().FirebaseConnect()
 	if err != nil {
 		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
@@ -193,46 +357,67 @@ func LikeListing(w http.ResponseWriter, r *http.Request) {
 	}
 
 	id := chi.URLParam(r, "id")
-	email := chi.URLParam(r, "email")
 
 	_, claims, _ := jwtauth.FromContext(r.Context())
 
 	storage := NewStorage()
-	err = storage.LikeListing(id, util.Base64Encode(email), claims["email"].(string))
+	err = storage.SetLike(id, claims["id"].(string), liked)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	w.Write([]byte("Like updated"))
+	if liked {
+		w.Write([]byte("Listing liked"))
+	} else {
+		w.Write([]byte("Listing unliked"))
+	}
 }
 
-func CommentListing(w http.ResponseWriter, r *http.Request) {
+func GetLikes(w http.ResponseWriter, r *http.Request) {
 	err := GetFirebaseDatabase().FirebaseConnect()
 	if err != nil {
 		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
 		return
 	}
 
+	limit, offset, ok := parsePage(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
 	id := chi.URLParam(r, "id")
-	email := chi.URLParam(r, "email")
 
-	var comment models.Comment
+	_, claims, _ := jwtauth.FromContext(r.Context())
 
-	err = json.NewDecoder(r.Body).Decode(&comment)
+	storage := NewStorage()
+	likes, err := storage.GetLikes(id, claims["id"].(string))
 	if err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
-	storage := NewStorage()
-	err = storage.CommentListing(id, util.Base64Encode(email), comment)
+	page := models.NewPage(likes, limit, offset)
+	for i, like := range page.Items {
+		user, err := storage.GetUser(like.UserID)
+		if errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		page.Items[i].Handle = user.Handle
+		page.Items[i].Name = user.Name
+	}
+
+	responseJSON, err := json.Marshal(page)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-
-	w.Write([]byte("Comment added"))
+	w.Write([]byte(responseJSON))
 }
 
 func UploadImage(w http.ResponseWriter, r *http.Request) {
@@ -244,6 +429,8 @@ func UploadImage(w http.ResponseWriter, r *http.Request) {
 
 	id := chi.URLParam(r, "id")
 
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
 	storage := NewStorage()
 
 	imageBytes, err := io.ReadAll(r.Body)
@@ -253,9 +440,9 @@ func UploadImage(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	image, err := storage.UploadImage(id, imageBytes)
+	image, err := storage.UploadImage(claims["id"].(string), id, imageBytes)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -267,7 +454,7 @@ func UploadImage(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(responseJSON))
 }
 
-func DeleteImage(w http.ResponseWriter, r *http.Request) {
+func GetImage(w http.ResponseWriter, r *http.Request) {
 	err := GetFirebaseDatabase().FirebaseConnect()
 	if err != nil {
 		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
@@ -276,12 +463,52 @@ func DeleteImage(w http.ResponseWriter, r *http.Request) {
 
 	id := chi.URLParam(r, "id")
 
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
 	storage := NewStorage()
-	err = storage.DeleteImage(id)
+	listing, err := storage.GetListingByID(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	visible, err := storage.CanView(listing, claims["id"].(string))
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	if !visible {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	image, err := storage.GetImage(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Write(image)
+}
+
+func DeleteImage(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	err = storage.DeleteImage(claims["id"].(string), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
 	w.Write([]byte("Image deleted"))
 }
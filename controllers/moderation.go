@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Ygnas/FoodLog/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+func ReportListing(w http.ResponseWriter, r *http.Request) {
+	report(w, r, (*Storage).ReportListing)
+}
+
+func ReportComment(w http.ResponseWriter, r *http.Request) {
+	report(w, r, (*Storage).ReportComment)
+}
+
+func report(w http.ResponseWriter, r *http.Request, save func(*Storage, string, string, models.Report) error) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	var report models.Report
+
+	err = json.NewDecoder(r.Body).Decode(&report)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	err = save(storage, id, claims["id"].(string), report)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Write([]byte("Report received"))
+}
+
+func GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	limit, offset, ok := parsePage(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	queue, err := storage.GetModerationQueue(claims["id"].(string))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(models.NewPage(queue, limit, offset))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
+
+// Moderate applies the action in the URL to a reported listing or comment. An optional
+// JSON body with a "note" is kept in the audit log.
+func Moderate(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	kind := models.ContentKind(chi.URLParam(r, "kind"))
+	if !kind.Valid() {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	action := models.ModerationAction(chi.URLParam(r, "action"))
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	entry, err := storage.Moderate(claims["id"].(string), kind, id, action, body.Note)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(entry)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
+
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	limit, offset, ok := parsePage(r)
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	audit, err := storage.GetAuditLog(claims["id"].(string))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(models.NewPage(audit, limit, offset))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
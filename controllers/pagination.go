@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parsePage reads the limit and offset query parameters of a paginated request.
+func parsePage(r *http.Request) (limit int, offset int, ok bool) {
+	limit, offset = defaultPageLimit, 0
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, false
+		}
+		limit = min(parsed, maxPageLimit)
+	}
+
+	if value := r.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, false
+		}
+		offset = parsed
+	}
+
+	return limit, offset, true
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Ygnas/FoodLog/models"
+)
+
+// parseListingQuery reads the filters of a listing query. Every nutrient can be
+// bounded with min_<nutrient> and max_<nutrient>, for example min_protein_g=20.
+func parseListingQuery(r *http.Request) (*models.ListingQuery, bool) {
+	query := &models.ListingQuery{}
+	values := r.URL.Query()
+
+	for _, nutrient := range models.Nutrients {
+		var bounds models.Range
+		for _, bound := range []struct {
+			param string
+			value **float64
+		}{
+			{"min_" + nutrient, &bounds.Min},
+			{"max_" + nutrient, &bounds.Max},
+		} {
+			value := values.Get(bound.param)
+			if value == "" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(parsed) {
+				return nil, false
+			}
+			*bound.value = &parsed
+		}
+
+		if bounds.Min != nil || bounds.Max != nil {
+			if query.Nutrients == nil {
+				query.Nutrients = make(map[string]models.Range)
+			}
+			query.Nutrients[nutrient] = bounds
+		}
+	}
+	return query, true
+}
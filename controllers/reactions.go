@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Ygnas/FoodLog/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+func SetReaction(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	var reaction models.Reaction
+
+	err = json.NewDecoder(r.Body).Decode(&reaction)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if !reaction.Type.Valid() {
+		writeError(w, models.ErrInvalidReaction)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	err = storage.SetReaction(id, claims["id"].(string), reaction.Type)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Write([]byte("Reaction updated"))
+}
+
+func ClearReaction(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	err = storage.SetReaction(id, claims["id"].(string), "")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Write([]byte("Reaction removed"))
+}
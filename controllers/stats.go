@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Ygnas/FoodLog/models"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+func GetDailyStats(w http.ResponseWriter, r *http.Request) {
+	stats(w, r, models.Daily)
+}
+
+func GetWeeklyStats(w http.ResponseWriter, r *http.Request) {
+	stats(w, r, models.Weekly)
+}
+
+func GetMonthlyStats(w http.ResponseWriter, r *http.Request) {
+	stats(w, r, models.Monthly)
+}
+
+// stats summarizes the listings of the user in the period containing the date query
+// parameter, today by default, with progress towards their goals in daily summaries.
+// Today is taken in the IANA time zone named by the tz query parameter, by default the
+// time zone of the user or UTC. Meals count on the local date they were eaten on, and
+// listings without that time on the date they were created on in that time zone.
+func stats(w http.ResponseWriter, r *http.Request, period models.Period) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+	userID := claims["id"].(string)
+
+	storage := NewStorage()
+	user, err := storage.GetUser(userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	location := user.Location()
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		if !models.ValidTimeZone(tz) {
+			writeError(w, models.ErrInvalidTimeZone)
+			return
+		}
+		location, _ = time.LoadLocation(tz)
+	}
+
+	date := time.Now().In(location)
+	if value := r.URL.Query().Get("date"); value != "" {
+		date, err = time.ParseInLocation(models.DateLayout, value, location)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	listings, err := storage.GetAllUserListings(userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	summary := models.NewNutritionSummary(period, date, listings)
+	if period == models.Daily && len(user.Goals) > 0 {
+		streaks := models.NewStreaks(user.Goals, date, listings)
+		summary.Goals = user.Goals.Progress(summary.Totals)
+		summary.Streaks = &streaks
+	}
+
+	responseJSON, err := json.Marshal(summary)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(responseJSON))
+}
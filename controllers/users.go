@@ -2,11 +2,13 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/Ygnas/FoodLog/models"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -26,6 +28,27 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !models.ValidEmail(user.Email) {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if user.Handle != "" && !models.ValidHandle(user.Handle) {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidTimeZone(user.TimeZone) {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if err := user.Goals.Validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := user.MealWindows.Validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
 	user.ID = uuid.New()
 	user.CreatedAt = time.Now()
 
@@ -39,7 +62,7 @@ func Register(w http.ResponseWriter, r *http.Request) {
 	storage := NewStorage()
 	err = storage.RegisterUser(&user)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -68,6 +91,10 @@ func Login(w http.ResponseWriter, r *http.Request) {
 
 	storage := NewStorage()
 	storedUser, err := storage.LoginUser(&user)
+	if errors.Is(err, ErrUserNotFound) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -82,6 +109,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	jwt := GetTokenAuth()
 	tokenString := jwt.GetToken(map[string]interface{}{
 		"exp":   time.Now().Add(time.Hour * 24 * 30).Unix(),
+		"id":    storedUser.ID.String(),
 		"name":  storedUser.Name,
 		"email": storedUser.Email,
 	})
@@ -102,12 +130,226 @@ func DeleteUserByID(w http.ResponseWriter, r *http.Request) {
 	}
 	id := chi.URLParam(r, "id")
 
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
 	storage := NewStorage()
-	err = storage.DeleteUser(id)
+	err = storage.DeleteUserAs(claims["id"].(string), id)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	w.Write([]byte("User deleted"))
 }
+
+func GetProfile(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	handle := chi.URLParam(r, "handle")
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	user, err := storage.GetUserByHandle(handle)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	listings, err := storage.GetSharedUserListings(user.ID.String(), claims["id"].(string))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(models.NewProfile(user, listings))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeWithETag(w, r, bodyETag(responseJSON), responseJSON)
+}
+
+// UpdateMe changes the profile fields of the authenticated user. Fields left out
+// of the request body keep their current values.
+func UpdateMe(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+	userID := claims["id"].(string)
+
+	storage := NewStorage()
+	user, err := storage.GetUser(userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	update := struct {
+		Name      *string `json:"name"`
+		Bio       *string `json:"bio"`
+		AvatarURL *string `json:"avatar_url"`
+		TimeZone  *string `json:"time_zone"`
+	}{&user.Name, &user.Bio, &user.AvatarURL, &user.TimeZone}
+
+	err = json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidTimeZone(user.TimeZone) {
+		writeError(w, models.ErrInvalidTimeZone)
+		return
+	}
+
+	user, err = storage.UpdateProfile(userID, user.Name, user.Bio, user.AvatarURL, user.TimeZone)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	responseJSON, err := json.Marshal(models.NewProfile(user, nil))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write(responseJSON)
+}
+
+// GetMyGoals returns the daily goals of the user.
+func GetMyGoals(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	user, err := storage.GetUser(claims["id"].(string))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeGoals(w, user.Goals)
+}
+
+// SetMyGoals replaces the daily goals of the user with the goals in the body, such as
+// {"kcal": {"min": 1800, "max": 2200}, "sugar_g": {"max": 50}}.
+func SetMyGoals(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	var goals models.Goals
+
+	err = json.NewDecoder(r.Body).Decode(&goals)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if err := goals.Validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	user, err := storage.SetGoals(claims["id"].(string), goals)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeGoals(w, user.Goals)
+}
+
+func writeGoals(w http.ResponseWriter, goals models.Goals) {
+	if goals == nil {
+		goals = models.Goals{}
+	}
+	responseJSON, err := json.Marshal(goals)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write(responseJSON)
+}
+
+// GetMyMealWindows returns the windows meal types are inferred from for the user.
+func GetMyMealWindows(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	user, err := storage.GetUser(claims["id"].(string))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeMealWindows(w, user.MealWindows)
+}
+
+// SetMyMealWindows replaces the meal windows of the user with the windows in the body,
+// such as [{"type": "breakfast", "start": "06:00", "end": "10:00"}]. An empty list
+// restores the defaults.
+func SetMyMealWindows(w http.ResponseWriter, r *http.Request) {
+	err := GetFirebaseDatabase().FirebaseConnect()
+	if err != nil {
+		http.Error(w, "Could not connect to Firebase", http.StatusInternalServerError)
+		return
+	}
+
+	var windows models.MealWindows
+
+	err = json.NewDecoder(r.Body).Decode(&windows)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if err := windows.Validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	storage := NewStorage()
+	user, err := storage.SetMealWindows(claims["id"].(string), windows)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeMealWindows(w, user.MealWindows)
+}
+
+func writeMealWindows(w http.ResponseWriter, windows models.MealWindows) {
+	if len(windows) == 0 {
+		windows = models.DefaultMealWindows
+	}
+	responseJSON, err := json.Marshal(windows)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write(responseJSON)
+}
@@ -0,0 +1,82 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/Ygnas/FoodLog/models"
+)
+
+var ErrInvalidConfig = errors.New("invalid filter config")
+
+// Config selects the rules of a pipeline. Zero limits disable their rule.
+type Config struct {
+	BlockedWords        []string `json:"blocked_words"`
+	FlaggedWords        []string `json:"flagged_words"`
+	MaxLinks            int      `json:"max_links"`
+	LinksAction         Action   `json:"links_action"`
+	RepeatWindowSeconds int      `json:"repeat_window_seconds"`
+	RepeatAction        Action   `json:"repeat_action"`
+	CommentsPerMinute   int      `json:"comments_per_minute"`
+	RateAction          Action   `json:"rate_action"`
+}
+
+// DefaultConfig is used when no config file is given.
+var DefaultConfig = Config{
+	MaxLinks:            3,
+	LinksAction:         Flag,
+	RepeatWindowSeconds: 600,
+	RepeatAction:        Reject,
+	CommentsPerMinute:   10,
+	RateAction:          Reject,
+}
+
+// LoadConfig reads a JSON config file. Fields left out keep their default values.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// New builds the pipeline described by config.
+func New(config Config) (*Pipeline, error) {
+	for _, action := range []Action{config.LinksAction, config.RepeatAction, config.RateAction} {
+		if !action.Valid() {
+			return nil, ErrInvalidConfig
+		}
+	}
+
+	pipeline := &Pipeline{}
+	if len(config.BlockedWords) > 0 {
+		pipeline.Rules = append(pipeline.Rules, NewWordList(Reject, config.BlockedWords))
+	}
+	if len(config.FlaggedWords) > 0 {
+		pipeline.Rules = append(pipeline.Rules, NewWordList(Flag, config.FlaggedWords))
+	}
+	if config.MaxLinks > 0 {
+		pipeline.Rules = append(pipeline.Rules, &LinkLimit{Action: config.LinksAction, Max: config.MaxLinks})
+	}
+	if config.RepeatWindowSeconds > 0 {
+		pipeline.Rules = append(pipeline.Rules, &RepeatedPost{
+			Action: config.RepeatAction,
+			Window: time.Duration(config.RepeatWindowSeconds) * time.Second,
+		})
+	}
+	if config.CommentsPerMinute > 0 {
+		pipeline.Rules = append(pipeline.Rules, &RateLimit{
+			Action: config.RateAction,
+			Kind:   models.KindComment,
+			Limit:  config.CommentsPerMinute,
+		})
+	}
+	return pipeline, nil
+}
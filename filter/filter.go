@@ -0,0 +1,93 @@
+// Package filter screens user-written content such as listing titles and comments
+// for profanity and spam before it is stored.
+package filter
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Ygnas/FoodLog/models"
+)
+
+// ErrRejected is returned for content a rule rejected.
+var ErrRejected = errors.New("content rejected by filter")
+
+// Action is what happens to content a rule matches.
+type Action string
+
+const (
+	// Reject refuses the content.
+	Reject Action = "reject"
+	// Flag stores the content and reports it to the moderation queue.
+	Flag Action = "flag"
+)
+
+// Valid reports whether a is a known action.
+func (a Action) Valid() bool {
+	return a == Reject || a == Flag
+}
+
+// Content is a piece of user-written content to screen.
+type Content struct {
+	UserID    string
+	Kind      models.ContentKind
+	Text      string
+	CreatedAt time.Time
+}
+
+// Result describes why a rule matched content.
+type Result struct {
+	Action Action
+	Rule   string
+	Reason models.ReportReason
+}
+
+// Rule checks content, returning nil when the content passes.
+type Rule interface {
+	Check(content *Content) *Result
+}
+
+// Pipeline runs content through its rules in order.
+type Pipeline struct {
+	Rules []Rule
+}
+
+// Run returns the first rejection, otherwise the first flag, or nil when every rule
+// passes. Rules after a rejection are not run. A nil pipeline lets everything through.
+func (p *Pipeline) Run(content *Content) *Result {
+	if p == nil {
+		return nil
+	}
+	if content.CreatedAt.IsZero() {
+		content.CreatedAt = time.Now()
+	}
+
+	var flagged *Result
+	for _, rule := range p.Rules {
+		result := rule.Check(content)
+		if result == nil {
+			continue
+		}
+		if result.Action == Reject {
+			return result
+		}
+		if flagged == nil {
+			flagged = result
+		}
+	}
+	return flagged
+}
+
+// words splits text into lowercase words of letters and digits.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// normalize collapses case and whitespace so trivially varied copies of a post match.
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ygnas/FoodLog/models"
+)
+
+// WordList matches content containing any of its words.
+type WordList struct {
+	Action Action
+	words  map[string]bool
+}
+
+// NewWordList builds a WordList matching the given words, case-insensitively.
+func NewWordList(action Action, list []string) *WordList {
+	w := &WordList{Action: action, words: make(map[string]bool, len(list))}
+	for _, word := range list {
+		w.words[strings.ToLower(strings.TrimSpace(word))] = true
+	}
+	return w
+}
+
+func (w *WordList) Check(content *Content) *Result {
+	for _, word := range words(content.Text) {
+		if w.words[word] {
+			return &Result{Action: w.Action, Rule: "word-list", Reason: models.ReasonInappropriate}
+		}
+	}
+	return nil
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// LinkLimit matches content with more than Max links.
+type LinkLimit struct {
+	Action Action
+	Max    int
+}
+
+func (l *LinkLimit) Check(content *Content) *Result {
+	if len(linkPattern.FindAllString(content.Text, -1)) > l.Max {
+		return &Result{Action: l.Action, Rule: "link-limit", Reason: models.ReasonSpam}
+	}
+	return nil
+}
+
+// RepeatedPost matches content a user already posted within Window.
+type RepeatedPost struct {
+	Action Action
+	Window time.Duration
+
+	mu    sync.Mutex
+	posts map[string]time.Time
+}
+
+func (r *RepeatedPost) Check(content *Content) *Result {
+	sum := sha256.Sum256([]byte(normalize(content.Text)))
+	key := content.UserID + "/" + string(content.Kind) + "/" + hex.EncodeToString(sum[:])
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.posts == nil {
+		r.posts = make(map[string]time.Time)
+	}
+	for k, postedAt := range r.posts {
+		if content.CreatedAt.Sub(postedAt) > r.Window {
+			delete(r.posts, k)
+		}
+	}
+
+	_, repeated := r.posts[key]
+	r.posts[key] = content.CreatedAt
+	if repeated {
+		return &Result{Action: r.Action, Rule: "repeated-post", Reason: models.ReasonSpam}
+	}
+	return nil
+}
+
+// RateLimit matches content of Kind once a user posted Limit items of it within the
+// last minute.
+type RateLimit struct {
+	Action Action
+	Kind   models.ContentKind
+	Limit  int
+
+	mu    sync.Mutex
+	posts map[string][]time.Time
+}
+
+func (r *RateLimit) Check(content *Content) *Result {
+	if content.Kind != r.Kind {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.posts == nil {
+		r.posts = make(map[string][]time.Time)
+	}
+	var recent []time.Time
+	for _, postedAt := range r.posts[content.UserID] {
+		if content.CreatedAt.Sub(postedAt) < time.Minute {
+			recent = append(recent, postedAt)
+		}
+	}
+
+	if len(recent) >= r.Limit {
+		r.posts[content.UserID] = recent
+		return &Result{Action: r.Action, Rule: "rate-limit", Reason: models.ReasonSpam}
+	}
+	r.posts[content.UserID] = append(recent, content.CreatedAt)
+	return nil
+}
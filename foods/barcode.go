@@ -0,0 +1,56 @@
+package foods
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Ygnas/FoodLog/models"
+)
+
+var (
+	ErrInvalidBarcode  = errors.New("invalid barcode")
+	ErrProductNotFound = errors.New("product not found")
+)
+
+// Product is a packaged food identified by its barcode, with its nutrient values per
+// 100 g and, when the serving size is known, per serving.
+type Product struct {
+	Barcode    string            `json:"barcode"`
+	Name       string            `json:"name"`
+	Brand      string            `json:"brand,omitempty"`
+	Category   string            `json:"category,omitempty"`
+	ServingG   *float64          `json:"serving_g,omitempty"`
+	Per100g    models.Nutrition  `json:"per_100g"`
+	PerServing *models.Nutrition `json:"per_serving,omitempty"`
+}
+
+// NormalizeBarcode checks the check digit of an EAN-13 or UPC-A barcode and returns
+// it as an EAN-13 code. UPC-A codes are EAN-13 codes with a leading zero.
+func NormalizeBarcode(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == 12 {
+		code = "0" + code
+	}
+	if len(code) != 13 {
+		return "", ErrInvalidBarcode
+	}
+
+	sum := 0
+	for i, digit := range code {
+		if digit < '0' || digit > '9' {
+			return "", ErrInvalidBarcode
+		}
+		if i == 12 {
+			break
+		}
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(digit-'0') * weight
+	}
+	if int(code[12]-'0') != (10-sum%10)%10 {
+		return "", ErrInvalidBarcode
+	}
+	return code, nil
+}
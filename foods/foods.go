@@ -0,0 +1,149 @@
+// Package foods is a local food composition database used to look up foods and to
+// compute the nutrition of ingredients linked to them.
+package foods
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Ygnas/FoodLog/models"
+)
+
+var ErrFoodNotFound = errors.New("food not found")
+
+// Food is an entry of the database with its nutrient values per 100 g.
+type Food struct {
+	ID       string           `json:"id"`
+	Name     string           `json:"name"`
+	Category string           `json:"category,omitempty"`
+	Per100g  models.Nutrition `json:"per_100g"`
+}
+
+// Database holds foods in memory, sorted by name.
+type Database struct {
+	foods []Food
+	byID  map[string]*Food
+}
+
+// NewDatabase builds a database out of foods. IDs must be unique.
+func NewDatabase(foods []Food) (*Database, error) {
+	database := &Database{foods: append([]Food(nil), foods...), byID: make(map[string]*Food, len(foods))}
+	sort.SliceStable(database.foods, func(i, j int) bool {
+		return database.foods[i].Name < database.foods[j].Name
+	})
+	for i := range database.foods {
+		food := &database.foods[i]
+		if _, ok := database.byID[food.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate food %q", ErrInvalidDataset, food.ID)
+		}
+		database.byID[food.ID] = food
+	}
+	return database, nil
+}
+
+// Len returns the number of foods in the database.
+func (d *Database) Len() int {
+	return len(d.foods)
+}
+
+// Get returns the food with the given ID.
+func (d *Database) Get(id string) (*Food, error) {
+	food, ok := d.byID[id]
+	if !ok {
+		return nil, ErrFoodNotFound
+	}
+	return food, nil
+}
+
+// Search returns the foods with a word in their name starting with each word of query,
+// best matches first: an exact name, then names starting with the query, then the
+// shortest names. An empty query returns every food.
+func (d *Database) Search(query string) []Food {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	terms := strings.Fields(query)
+
+	type match struct {
+		food Food
+		rank int
+	}
+	var matches []match
+	for _, food := range d.foods {
+		name := strings.ToLower(food.Name)
+		if !matchesAll(name, terms) {
+			continue
+		}
+		matches = append(matches, match{food: food, rank: searchRank(name, query)})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return len(matches[i].food.Name) < len(matches[j].food.Name)
+	})
+
+	foods := make([]Food, len(matches))
+	for i, match := range matches {
+		foods[i] = match.food
+	}
+	return foods
+}
+
+func matchesAll(name string, terms []string) bool {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, term := range terms {
+		if !slices.ContainsFunc(words, func(word string) bool { return strings.HasPrefix(word, term) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func searchRank(name string, query string) int {
+	switch {
+	case query == "" || name == query:
+		return 0
+	case strings.HasPrefix(name, query):
+		return 1
+	}
+	return 2
+}
+
+// Nutrition adds up the nutrition of ingredients linked to foods. It reports false
+// when an ingredient is not linked to a food or its weight in grams is unknown, and
+// returns ErrFoodNotFound for links to foods missing from the database.
+func (d *Database) Nutrition(ingredients []models.Ingredient) (*models.Nutrition, bool, error) {
+	if len(ingredients) == 0 {
+		return nil, false, nil
+	}
+
+	var total models.Nutrition
+	complete := true
+	for _, ingredient := range ingredients {
+		if ingredient.FoodID == "" {
+			complete = false
+			continue
+		}
+		food, err := d.Get(ingredient.FoodID)
+		if err != nil {
+			return nil, false, err
+		}
+		if ingredient.Grams == nil {
+			complete = false
+			continue
+		}
+
+		total.Add(food.Per100g.Scaled(*ingredient.Grams / 100))
+	}
+	if !complete {
+		return nil, false, nil
+	}
+
+	rounded := total.Rounded()
+	return &rounded, true, nil
+}
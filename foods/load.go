@@ -0,0 +1,268 @@
+package foods
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Ygnas/FoodLog/models"
+)
+
+var ErrInvalidDataset = errors.New("invalid food dataset")
+
+// Dataset file formats.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+//go:embed data/foods.csv
+var bundled []byte
+
+// record is a row of a dataset file. CSV files name the same columns in their header.
+// Foods are identified by their id and packaged products by their barcode.
+type record struct {
+	ID          string   `json:"id"`
+	Barcode     string   `json:"barcode"`
+	Description string   `json:"description"`
+	Brand       string   `json:"brand"`
+	Category    string   `json:"category"`
+	ServingG    *float64 `json:"serving_g"`
+	EnergyKcal  float64  `json:"energy_kcal"`
+	ProteinG    float64  `json:"protein_g"`
+	CarbsG      float64  `json:"carbohydrate_g"`
+	FatG        float64  `json:"fat_g"`
+	FiberG      float64  `json:"fiber_g"`
+	SugarsG     float64  `json:"sugars_g"`
+	SodiumMg    float64  `json:"sodium_mg"`
+}
+
+func (r *record) nutrition() (models.Nutrition, error) {
+	nutrition := models.Nutrition{
+		Kcal:     r.EnergyKcal,
+		ProteinG: r.ProteinG,
+		CarbsG:   r.CarbsG,
+		FatG:     r.FatG,
+		FiberG:   r.FiberG,
+		SugarG:   r.SugarsG,
+		SodiumMg: r.SodiumMg,
+	}
+	return nutrition, nutrition.Validate()
+}
+
+func (r *record) food() (Food, error) {
+	food := Food{
+		ID:       strings.TrimSpace(r.ID),
+		Name:     strings.TrimSpace(r.Description),
+		Category: strings.TrimSpace(r.Category),
+	}
+	if food.ID == "" || food.Name == "" {
+		return food, fmt.Errorf("%w: food without id or description", ErrInvalidDataset)
+	}
+	per100g, err := r.nutrition()
+	if err != nil {
+		return food, fmt.Errorf("%w: food %q: %v", ErrInvalidDataset, food.ID, err)
+	}
+	food.Per100g = per100g
+	return food, nil
+}
+
+func (r *record) product() (Product, error) {
+	product := Product{
+		Name:     strings.TrimSpace(r.Description),
+		Brand:    strings.TrimSpace(r.Brand),
+		Category: strings.TrimSpace(r.Category),
+	}
+	barcode, err := NormalizeBarcode(r.Barcode)
+	if err != nil {
+		return product, fmt.Errorf("%w: product %q: %v", ErrInvalidDataset, r.Barcode, err)
+	}
+	product.Barcode = barcode
+	if product.Name == "" {
+		return product, fmt.Errorf("%w: product %q without description", ErrInvalidDataset, barcode)
+	}
+	per100g, err := r.nutrition()
+	if err != nil {
+		return product, fmt.Errorf("%w: product %q: %v", ErrInvalidDataset, barcode, err)
+	}
+	product.Per100g = per100g
+
+	if r.ServingG != nil {
+		// Written to also refuse NaN, which strconv.ParseFloat accepts.
+		if !(*r.ServingG > 0 && *r.ServingG <= 10000) {
+			return product, fmt.Errorf("%w: product %q: invalid serving_g", ErrInvalidDataset, barcode)
+		}
+		perServing := per100g.Scaled(*r.ServingG / 100).Rounded()
+		product.ServingG = r.ServingG
+		product.PerServing = &perServing
+	}
+	return product, nil
+}
+
+// Bundled returns the database shipped with the server.
+func Bundled() (*Database, error) {
+	return ParseCSV(bytes.NewReader(bundled))
+}
+
+// Load reads a dataset file, in CSV or JSON depending on its extension.
+func Load(path string) (*Database, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := readRecords(file, strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."))
+	if err != nil {
+		return nil, err
+	}
+	return newDatabase(records)
+}
+
+// ParseCSV reads a CSV dataset of foods with a header row naming its columns. The id
+// and description columns are required; missing nutrient columns are read as zero.
+func ParseCSV(reader io.Reader) (*Database, error) {
+	records, err := readRecords(reader, FormatCSV)
+	if err != nil {
+		return nil, err
+	}
+	return newDatabase(records)
+}
+
+// ParseJSON reads a JSON dataset of foods: an array of objects with the same fields
+// as the CSV columns.
+func ParseJSON(reader io.Reader) (*Database, error) {
+	records, err := readRecords(reader, FormatJSON)
+	if err != nil {
+		return nil, err
+	}
+	return newDatabase(records)
+}
+
+// ParseProducts reads a dataset of packaged products in the given format. Products
+// have the columns of foods, with a barcode in place of the id and an optional brand
+// and serving size in grams.
+func ParseProducts(reader io.Reader, format string) ([]Product, error) {
+	records, err := readRecords(reader, format)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]Product, 0, len(records))
+	seen := make(map[string]bool, len(records))
+	for _, r := range records {
+		product, err := r.product()
+		if err != nil {
+			return nil, err
+		}
+		if seen[product.Barcode] {
+			return nil, fmt.Errorf("%w: duplicate product %q", ErrInvalidDataset, product.Barcode)
+		}
+		seen[product.Barcode] = true
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+func newDatabase(records []record) (*Database, error) {
+	foods := make([]Food, 0, len(records))
+	for _, r := range records {
+		food, err := r.food()
+		if err != nil {
+			return nil, err
+		}
+		foods = append(foods, food)
+	}
+	return NewDatabase(foods)
+}
+
+func readRecords(reader io.Reader, format string) ([]record, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(reader)
+	case FormatJSON:
+		var records []record
+		if err := json.NewDecoder(reader).Decode(&records); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDataset, err)
+		}
+		return records, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidDataset, format)
+}
+
+func readCSV(reader io.Reader) ([]record, error) {
+	rows, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDataset, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidDataset)
+	}
+
+	columns := make(map[string]int)
+	for index, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+	if _, ok := columns["description"]; !ok {
+		return nil, fmt.Errorf("%w: missing column %q", ErrInvalidDataset, "description")
+	}
+
+	records := make([]record, 0, len(rows)-1)
+	for line, row := range rows[1:] {
+		text := func(column string) string {
+			if index, ok := columns[column]; ok {
+				return strings.TrimSpace(row[index])
+			}
+			return ""
+		}
+		number := func(column string) (float64, bool, error) {
+			field := text(column)
+			if field == "" {
+				return 0, false, nil
+			}
+			value, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return 0, false, fmt.Errorf("%w: line %d: invalid %s", ErrInvalidDataset, line+2, column)
+			}
+			return value, true, nil
+		}
+
+		r := record{
+			ID:          text("id"),
+			Barcode:     text("barcode"),
+			Description: text("description"),
+			Brand:       text("brand"),
+			Category:    text("category"),
+		}
+		for column, value := range map[string]*float64{
+			"energy_kcal":    &r.EnergyKcal,
+			"protein_g":      &r.ProteinG,
+			"carbohydrate_g": &r.CarbsG,
+			"fat_g":          &r.FatG,
+			"fiber_g":        &r.FiberG,
+			"sugars_g":       &r.SugarsG,
+			"sodium_mg":      &r.SodiumMg,
+		} {
+			if *value, _, err = number(column); err != nil {
+				return nil, err
+			}
+		}
+		serving, ok, err := number("serving_g")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			r.ServingG = &serving
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
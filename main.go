@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/Ygnas/FoodLog/controllers"
 	"github.com/go-chi/chi/v5"
@@ -30,6 +31,8 @@ func CreateNewRouter() *Router {
 
 func (r *Router) MountRoutes() {
 	controllers.NewJwt()
+	controllers.NewContentFilter()
+	controllers.NewFoodDatabase()
 	jwt := controllers.GetTokenAuth()
 
 	r.Router.Use(middleware.Logger)
@@ -42,16 +45,55 @@ func (r *Router) MountRoutes() {
 	r.Router.Group(func(r chi.Router) {
 		r.Use(jwtauth.Verifier(jwt.TokenAuth))
 		r.Use(jwtauth.Authenticator(jwt.TokenAuth))
+		r.Use(controllers.RequireUserID)
 
 		r.Get("/listings", controllers.GetAllUserListings)
 		r.Get("/all-listings", controllers.GetAllListings)
 		r.Get("/listings/{id}", controllers.GetListing)
 		r.Post("/listings", controllers.CreateListing)
 		r.Put("/listings/{id}", controllers.UpdateListing)
+		r.Patch("/listings/{id}", controllers.PatchListing)
 		r.Delete("/listings/{id}", controllers.DeleteListing)
 		r.Delete("/users/delete/{id}", controllers.DeleteUserByID)
-		r.Post("/listings/{id}/{email}/like", controllers.LikeListing)
-		r.Post("/listings/{id}/{email}/comment", controllers.CommentListing)
+		r.Patch("/users/me", controllers.UpdateMe)
+		r.Get("/users/me/goals", controllers.GetMyGoals)
+		r.Put("/users/me/goals", controllers.SetMyGoals)
+		r.Get("/users/me/meal-windows", controllers.GetMyMealWindows)
+		r.Put("/users/me/meal-windows", controllers.SetMyMealWindows)
+		r.Get("/users/{handle}", controllers.GetProfile)
+		r.Put("/users/{handle}/follow", controllers.FollowUser)
+		r.Delete("/users/{handle}/follow", controllers.UnfollowUser)
+		r.Get("/users/{handle}/followers", controllers.GetFollowers)
+		r.Get("/users/{handle}/following", controllers.GetFollowing)
+		r.Get("/users/me/blocks", controllers.GetBlocked)
+		r.Get("/users/me/mutes", controllers.GetMuted)
+		r.Put("/users/{handle}/block", controllers.BlockUser)
+		r.Delete("/users/{handle}/block", controllers.UnblockUser)
+		r.Put("/users/{handle}/mute", controllers.MuteUser)
+		r.Delete("/users/{handle}/mute", controllers.UnmuteUser)
+		r.Get("/feed", controllers.GetFeed)
+		r.Get("/stats/daily", controllers.GetDailyStats)
+		r.Get("/stats/weekly", controllers.GetWeeklyStats)
+		r.Get("/stats/monthly", controllers.GetMonthlyStats)
+		r.Get("/listings/{id}/likes", controllers.GetLikes)
+		r.Put("/listings/{id}/likes/me", controllers.LikeListing)
+		r.Delete("/listings/{id}/likes/me", controllers.UnlikeListing)
+		r.Put("/listings/{id}/reactions/me", controllers.SetReaction)
+		r.Delete("/listings/{id}/reactions/me", controllers.ClearReaction)
+		r.Post("/listings/{id}/comment", controllers.CommentListing)
+		r.Get("/listings/{id}/comments", controllers.GetComments)
+		r.Patch("/listings/{id}/comments/{commentId}", controllers.UpdateComment)
+		r.Delete("/listings/{id}/comments/{commentId}", controllers.DeleteComment)
+		r.Get("/listings/{id}/image", controllers.GetImage)
+		r.Get("/foods", controllers.SearchFoods)
+		r.Get("/foods/{id}", controllers.GetFood)
+		r.Get("/foods/barcode/{ean}", controllers.GetProductByBarcode)
+		r.Post("/admin/products", controllers.ImportProducts)
+		r.Post("/listings/{id}/report", controllers.ReportListing)
+		r.Post("/comments/{id}/report", controllers.ReportComment)
+		r.Get("/moderation/queue", controllers.GetModerationQueue)
+		r.Get("/moderation/audit", controllers.GetAuditLog)
+		r.Post("/moderation/{kind}/{id}/{action}", controllers.Moderate)
 
 		r.Post("/upload/{id}", controllers.UploadImage)
 		r.Delete("/images/{id}/delete", controllers.DeleteImage)
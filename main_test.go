@@ -2,15 +2,22 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/Ygnas/FoodLog/controllers"
+	"github.com/Ygnas/FoodLog/filter"
+	"github.com/Ygnas/FoodLog/foods"
 	"github.com/Ygnas/FoodLog/models"
-	"github.com/Ygnas/FoodLog/util"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -24,17 +31,16 @@ func executeRequest(req *http.Request, r *Router) *httptest.ResponseRecorder {
 var newListing = models.Listing{
 	Title:       "Test",
 	Description: "Test",
-	Shared:      true,
+	Visibility:  models.Public,
 	Image:       "Test",
 	Type:        models.Snack,
-	Likes: []models.Like{
-		{Email: "test@test.com"},
+	Reactions: map[string]models.Reaction{
+		"test-user": {UserID: "test-user", Type: models.ReactionLike},
 	},
-	Comments: []models.Comment{
-		{Email: "test@test.com", Comment: "Test", CreatedAt: time.Now()},
+	Comments: map[string]models.Comment{
+		"test": {UserID: "test-user", Comment: "Test", CreatedAt: time.Now()},
 	},
 	Location:  models.Location{Latitude: 0, Longitude: 0},
-	UserEmail: "gotest@gotest.com",
 	CreatedAt: time.Now(),
 	UpdatedAt: time.Now(),
 }
@@ -42,16 +48,56 @@ var newListing = models.Listing{
 var newUser = models.User{
 	Email:    "gotest@gotest.com",
 	Name:     "gotest",
+	Handle:   "gotest",
 	Password: "gotest",
 }
 
 var comment = models.Comment{
-	Email:     "gotest@gotest.com",
 	Comment:   "Test comment",
 	CreatedAt: time.Now(),
 }
 
+var otherUser = models.User{
+	Email:    "gotest-other@gotest.com",
+	Name:     "gotest-other",
+	Password: "gotest",
+}
+
+var privateListing = models.Listing{
+	Title:       "Private",
+	Description: "Private",
+	Visibility:  models.Private,
+	Type:        models.Dinner,
+}
+
+var usersListing = models.Listing{
+	Title:       "For my coach",
+	Description: "For my coach",
+	Visibility:  models.Users,
+	Type:        models.Lunch,
+}
+
+var followersListing = models.Listing{
+	Title:       "For my followers",
+	Description: "For my followers",
+	Visibility:  models.Followers,
+	Type:        models.Breakfast,
+}
+
+var reply models.Comment
+
 var testToken string
+var otherToken string
+
+func getETag(t *testing.T, r *Router, id string, token string) string {
+	req, _ := http.NewRequest("GET", "/listings/"+id, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NotEmpty(t, response.Header().Get("ETag"))
+	return response.Header().Get("ETag")
+}
 
 func TestRegister(t *testing.T) {
 	r := CreateNewRouter()
@@ -65,7 +111,11 @@ func TestRegister(t *testing.T) {
 	response := executeRequest(req, r)
 
 	require.Equal(t, http.StatusOK, response.Code)
-	require.NotEmpty(t, response.Body.String())
+
+	var registered models.User
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&registered))
+	require.NotEqual(t, uuid.Nil, registered.ID)
+	newUser.ID = registered.ID
 }
 
 func TestLogin(t *testing.T) {
@@ -84,6 +134,39 @@ func TestLogin(t *testing.T) {
 	require.NotEmpty(t, response.Body.String())
 }
 
+func TestRegisterDuplicateEmail(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	duplicate := newUser
+	duplicate.Handle = ""
+	jsonInput, err := json.Marshal(duplicate)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/users/register", bytes.NewBuffer(jsonInput))
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestTokenWithoutUserID(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	token := controllers.GetTokenAuth().GetToken(map[string]interface{}{
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"email": newUser.Email,
+	})
+
+	req, _ := http.NewRequest("GET", "/listings", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusUnauthorized, response.Code)
+}
+
 func TestGetListingEmpty(t *testing.T) {
 	r := CreateNewRouter()
 
@@ -93,8 +176,48 @@ func TestGetListingEmpty(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer "+testToken)
 	response := executeRequest(req, r)
 
-	require.Equal(t, http.StatusOK, response.Code)
-	require.NotEmpty(t, response.Body.String())
+	require.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestUpdateListingMissing(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	jsonInput, err := json.Marshal(models.Listing{ID: uuid.New(), Title: "Missing"})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("PUT", "/listings/00000", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", `"1"`)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestDeleteListingMissing(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("DELETE", "/listings/00000", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", `"1"`)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestLikeListingMissing(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("PUT", "/listings/00000/likes/me", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
 }
 
 func TestCreateListing(t *testing.T) {
@@ -116,6 +239,9 @@ func TestCreateListing(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	require.Zero(t, newListing.LikeCount)
+	require.Zero(t, newListing.CommentCount)
+	require.Empty(t, newListing.Comments)
 }
 
 func TestGetListing(t *testing.T) {
@@ -156,16 +282,113 @@ func TestUpdateListing(t *testing.T) {
 	var listing models.Listing
 
 	newListing.Title = "Test-updated"
-	jsonInput, err := json.Marshal(newListing)
+	update := newListing
+	update.UserID = "someone-else"
+	update.CreatedAt = time.Time{}
+	jsonInput, err := json.Marshal(update)
 	require.NoError(t, err)
 
 	req, _ := http.NewRequest("PUT", "/listings/"+newListing.ID.String(), bytes.NewBuffer(jsonInput))
 	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, newListing.ID.String(), testToken))
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	json.NewDecoder(response.Body).Decode(&listing)
+	require.Equal(t, "Test-updated", listing.Title)
+	require.Equal(t, newUser.ID.String(), listing.UserID)
+	require.False(t, listing.CreatedAt.IsZero())
+}
+
+func TestPatchListing(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var listing models.Listing
+
+	patch := []byte(`{"description": "Test-patched", "id": "00000000-0000-0000-0000-000000000000", "user_id": "someone-else"}`)
+
+	req, _ := http.NewRequest("PATCH", "/listings/"+newListing.ID.String(), bytes.NewBuffer(patch))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, newListing.ID.String(), testToken))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
 	response := executeRequest(req, r)
 
 	require.Equal(t, http.StatusOK, response.Code)
 	json.NewDecoder(response.Body).Decode(&listing)
 	require.Equal(t, "Test-updated", listing.Title)
+	require.Equal(t, "Test-patched", listing.Description)
+	require.Equal(t, newListing.ID, listing.ID)
+	require.Equal(t, newUser.ID.String(), listing.UserID)
+}
+
+func TestPatchListingInvalidVisibility(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("PATCH", "/listings/"+newListing.ID.String(), bytes.NewBuffer([]byte(`{"visibility": "everyone"}`)))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, newListing.ID.String(), testToken))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestUpdateListingWithoutIfMatch(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	jsonInput, err := json.Marshal(newListing)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("PUT", "/listings/"+newListing.ID.String(), bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusPreconditionRequired, response.Code)
+}
+
+func TestUpdateListingStaleETag(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	jsonInput, err := json.Marshal(newListing)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("PUT", "/listings/"+newListing.ID.String(), bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", `"1"`)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusPreconditionFailed, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+newListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", `"1"`)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusPreconditionFailed, response.Code)
+}
+
+func TestGetListingNotModified(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	etag := getETag(t, r, newListing.ID.String(), testToken)
+
+	req, _ := http.NewRequest("GET", "/listings/"+newListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-None-Match", etag)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotModified, response.Code)
+	require.Empty(t, response.Body.String())
 }
 
 func TestUploadImage(t *testing.T) {
@@ -180,6 +403,10 @@ func TestUploadImage(t *testing.T) {
 	response := executeRequest(req, r)
 
 	require.Equal(t, http.StatusOK, response.Code)
+
+	var image string
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&image))
+	require.Equal(t, "/listings/"+newListing.ID.String()+"/image", image)
 }
 
 func TestDeleteImage(t *testing.T) {
@@ -192,6 +419,24 @@ func TestDeleteImage(t *testing.T) {
 	response := executeRequest(req, r)
 
 	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("GET", "/listings/"+newListing.ID.String()+"/image", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/images/"+newListing.ID.String()+"/delete", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
+
+	req, _ = http.NewRequest("GET", "/listings/"+uuid.New().String()+"/image", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
 }
 
 func TestLikeListing(t *testing.T) {
@@ -201,7 +446,7 @@ func TestLikeListing(t *testing.T) {
 
 	var listing models.Listing
 
-	req, _ := http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/"+newListing.UserEmail+"/like", nil)
+	req, _ := http.NewRequest("PUT", "/listings/"+newListing.ID.String()+"/likes/me", nil)
 	req.Header.Set("Authorization", "Bearer "+testToken)
 	response := executeRequest(req, r)
 
@@ -213,7 +458,54 @@ func TestLikeListing(t *testing.T) {
 
 	json.NewDecoder(response.Body).Decode(&listing)
 
-	require.Equal(t, 2, len(listing.Likes))
+	require.Equal(t, 1, listing.LikeCount)
+	require.True(t, listing.LikedByMe)
+	require.Empty(t, listing.Reactions)
+}
+
+func TestLikeListingIdempotent(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var listing models.Listing
+
+	for _, method := range []string{"PUT", "DELETE", "DELETE", "PUT", "PUT"} {
+		req, _ := http.NewRequest(method, "/listings/"+newListing.ID.String()+"/likes/me", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	}
+
+	req, _ := http.NewRequest("GET", "/listings/"+newListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	json.NewDecoder(response.Body).Decode(&listing)
+
+	require.Equal(t, 1, listing.LikeCount)
+}
+
+func TestGetLikes(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var page models.Page[models.Like]
+
+	req, _ := http.NewRequest("GET", "/listings/"+newListing.ID.String()+"/likes?limit=1", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&page))
+	require.Equal(t, 1, page.Total)
+	require.Len(t, page.Items, 1)
+	require.Equal(t, newUser.ID.String(), page.Items[0].UserID)
+	require.Equal(t, newUser.Handle, page.Items[0].Handle)
+	require.Equal(t, newUser.Name, page.Items[0].Name)
+	require.NotContains(t, response.Body.String(), newUser.Email)
 }
 
 func TestCommentListing(t *testing.T) {
@@ -226,11 +518,13 @@ func TestCommentListing(t *testing.T) {
 	jsonInput, err := json.Marshal(comment)
 	require.NoError(t, err)
 
-	req, _ := http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/"+newListing.UserEmail+"/comment", bytes.NewBuffer(jsonInput))
+	req, _ := http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", bytes.NewBuffer(jsonInput))
 	req.Header.Set("Authorization", "Bearer "+testToken)
 	response := executeRequest(req, r)
 
 	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&comment))
+	require.NotEmpty(t, comment.ID)
 
 	req, _ = http.NewRequest("GET", "/listings/"+newListing.ID.String(), nil)
 	req.Header.Set("Authorization", "Bearer "+testToken)
@@ -238,46 +532,1893 @@ func TestCommentListing(t *testing.T) {
 
 	json.NewDecoder(response.Body).Decode(&listing)
 
-	require.Equal(t, 2, len(listing.Comments))
+	require.Equal(t, 1, listing.CommentCount)
+	require.Empty(t, listing.Comments)
 }
 
-func TestDeleteListing(t *testing.T) {
+func TestRegisterOtherUser(t *testing.T) {
 	r := CreateNewRouter()
 
 	r.MountRoutes()
 
-	req, _ := http.NewRequest("DELETE", "/listings/"+newListing.ID.String(), nil)
-	req.Header.Set("Authorization", "Bearer "+testToken)
+	jsonInput, err := json.Marshal(otherUser)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/users/register", bytes.NewBuffer(jsonInput))
 	response := executeRequest(req, r)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var registered models.User
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&registered))
+	otherUser.ID = registered.ID
+	otherUser.Handle = registered.Handle
 
+	req, _ = http.NewRequest("POST", "/users/login", bytes.NewBuffer(jsonInput))
+	response = executeRequest(req, r)
 	require.Equal(t, http.StatusOK, response.Code)
-	require.Equal(t, "Listing deleted", response.Body.String())
+
+	otherToken = response.Body.String()
+	require.NotEmpty(t, otherToken)
 }
 
-func TestGetAllListings(t *testing.T) {
+func TestCreateListingIgnoresClientID(t *testing.T) {
 	r := CreateNewRouter()
 
 	r.MountRoutes()
 
-	req, _ := http.NewRequest("GET", "/all-listings", nil)
+	var created models.Listing
+
+	jsonInput, err := json.Marshal(models.Listing{ID: newListing.ID, Title: "Takeover", Visibility: models.Public})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&created))
+	require.NotEqual(t, newListing.ID, created.ID)
+
+	var listing models.Listing
+
+	req, _ = http.NewRequest("GET", "/listings/"+newListing.ID.String(), nil)
 	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+	require.Equal(t, newUser.ID.String(), listing.UserID)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+created.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	req.Header.Set("If-Match", getETag(t, r, created.ID.String(), otherToken))
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestImageOwnership(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("POST", "/upload/"+newListing.ID.String(), bytes.NewBuffer([]byte("notimage")))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/images/"+newListing.ID.String()+"/delete", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+}
+
+func TestRegisterHandleValidation(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	for handle, code := range map[string]int{
+		"Not A Handle": http.StatusBadRequest,
+		"me":           http.StatusBadRequest,
+		newUser.Handle: http.StatusConflict,
+	} {
+		jsonInput, err := json.Marshal(models.User{
+			Email:    "gotest-handle@gotest.com",
+			Name:     "gotest-handle",
+			Handle:   handle,
+			Password: "gotest",
+		})
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest("POST", "/users/register", bytes.NewBuffer(jsonInput))
+		response := executeRequest(req, r)
+		require.Equal(t, code, response.Code, handle)
+	}
+
+	for _, settings := range []string{
+		`"goals":{"kcal":{"min":2000,"max":1000}}`,
+		`"meal_windows":[{"type":"brunch","start":"10:00","end":"12:00"}]`,
+	} {
+		body := `{"email":"gotest-settings@gotest.com","name":"gotest-settings","password":"gotest",` + settings + `}`
+		req, _ := http.NewRequest("POST", "/users/register", strings.NewReader(body))
+		response := executeRequest(req, r)
+		require.Equal(t, http.StatusBadRequest, response.Code, settings)
+	}
+
+	for _, email := range []string{"", "not-an-email", "Gotest <gotest-email@gotest.com>"} {
+		jsonInput, err := json.Marshal(models.User{Email: email, Name: "gotest-email", Password: "gotest"})
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest("POST", "/users/register", bytes.NewBuffer(jsonInput))
+		response := executeRequest(req, r)
+		require.Equal(t, http.StatusBadRequest, response.Code, email)
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var profile models.Profile
+
+	req, _ := http.NewRequest("GET", "/users/"+newUser.Handle, nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
 	response := executeRequest(req, r)
 
 	require.Equal(t, http.StatusOK, response.Code)
-	require.NotEmpty(t, response.Body.String())
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&profile))
+	require.Equal(t, newUser.Handle, profile.Handle)
+	require.Equal(t, newUser.Name, profile.Name)
+	require.False(t, profile.JoinedAt.IsZero())
+	require.Equal(t, 1, profile.Stats.TotalMeals)
+	require.Equal(t, newListing.Type, profile.Stats.MostCommonMealType)
+	require.NotContains(t, response.Body.String(), newUser.Email)
+
+	req, _ = http.NewRequest("GET", "/users/nobody", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
 }
 
-func TestDeleteUserByID(t *testing.T) {
+func TestUpdateMe(t *testing.T) {
 	r := CreateNewRouter()
 
 	r.MountRoutes()
 
-	req, _ := http.NewRequest("DELETE", "/users/delete/"+util.Base64Encode(newUser.Email), nil)
+	var profile models.Profile
+
+	req, _ := http.NewRequest("PATCH", "/users/me", bytes.NewBuffer([]byte(`{"bio": "Eats a lot of snacks"}`)))
 	req.Header.Set("Authorization", "Bearer "+testToken)
 	response := executeRequest(req, r)
 
 	require.Equal(t, http.StatusOK, response.Code)
-	require.Equal(t, "User deleted", response.Body.String())
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&profile))
+	require.Equal(t, "Eats a lot of snacks", profile.Bio)
+	require.Equal(t, newUser.Name, profile.Name)
+}
+
+func TestReplyToComment(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	jsonInput, err := json.Marshal(models.Comment{Comment: "Reply", ParentID: comment.ID, CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&reply))
+	require.Equal(t, comment.ID, reply.ParentID)
+
+	jsonInput, err = json.Marshal(models.Comment{Comment: "Nested", ParentID: reply.ID, CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestGetComments(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var page models.Page[models.Comment]
+
+	req, _ := http.NewRequest("GET", "/listings/"+newListing.ID.String()+"/comments?limit=100", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&page))
+
+	found := false
+	for _, item := range page.Items {
+		require.Empty(t, item.ParentID)
+		if item.ID == comment.ID {
+			found = true
+			require.Len(t, item.Replies, 1)
+			require.Equal(t, reply.ID, item.Replies[0].ID)
+		}
+	}
+	require.True(t, found)
+}
+
+func TestUpdateComment(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var updated models.Comment
+
+	jsonInput, err := json.Marshal(models.Comment{Comment: "Edited"})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("PATCH", "/listings/"+newListing.ID.String()+"/comments/"+comment.ID, bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+
+	req, _ = http.NewRequest("PATCH", "/listings/"+newListing.ID.String()+"/comments/"+reply.ID, bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&updated))
+	require.Equal(t, "Edited", updated.Comment)
+	require.Equal(t, reply.UserID, updated.UserID)
+}
+
+func TestDeleteComment(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("DELETE", "/listings/"+newListing.ID.String()+"/comments/"+reply.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+newListing.ID.String()+"/comments/"+reply.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestCommentAuthorFromToken(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var created models.Comment
+
+	spoofed := models.Comment{
+		UserID:    newUser.ID.String(),
+		Name:      newUser.Name,
+		Comment:   "Spoofed",
+		CreatedAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	jsonInput, err := json.Marshal(spoofed)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&created))
+	require.Equal(t, otherUser.ID.String(), created.UserID)
+	require.Equal(t, otherUser.Name, created.Name)
+	require.WithinDuration(t, time.Now(), created.CreatedAt, time.Minute)
+}
+
+func TestCommentValidation(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	for _, text := range []string{"", "   ", strings.Repeat("a", models.MaxCommentLength+1)} {
+		jsonInput, err := json.Marshal(models.Comment{Comment: text})
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", bytes.NewBuffer(jsonInput))
+		req.Header.Set("Authorization", "Bearer "+otherToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	}
+}
+
+func TestReportContent(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	for path, body := range map[string]string{
+		"/listings/" + newListing.ID.String() + "/report": `{"reason": "spam"}`,
+		"/comments/" + comment.ID + "/report":             `{"reason": "harassment", "note": "Rude"}`,
+	} {
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer([]byte(body)))
+		req.Header.Set("Authorization", "Bearer "+otherToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code, path)
+	}
+
+	req, _ := http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/report", bytes.NewBuffer([]byte(`{"reason": "boring"}`)))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+
+	req, _ = http.NewRequest("GET", "/moderation/queue", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+}
+
+func TestModerateContent(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	require.NoError(t, controllers.GetFirebaseDatabase().FirebaseConnect())
+	storage := controllers.NewStorage()
+	require.NoError(t, storage.SetModerator(otherUser.ID.String(), true))
+	defer storage.SetModerator(otherUser.ID.String(), false)
+
+	var queue models.Page[models.ModerationItem]
+
+	req, _ := http.NewRequest("GET", "/moderation/queue", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&queue))
+	reported := make(map[string]models.ModerationItem)
+	for _, item := range queue.Items {
+		reported[item.ID] = item
+	}
+	require.Equal(t, 1, reported[newListing.ID.String()].ReasonCounts[models.ReasonSpam])
+	require.Equal(t, 1, reported[comment.ID].ReasonCounts[models.ReasonHarassment])
+
+	moderate := func(kind string, id string, action models.ModerationAction) {
+		req, _ := http.NewRequest("POST", "/moderation/"+kind+"/"+id+"/"+string(action), nil)
+		req.Header.Set("Authorization", "Bearer "+otherToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	}
+
+	moderate("listings", newListing.ID.String(), models.ActionHide)
+
+	req, _ = http.NewRequest("GET", "/listings/"+newListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+
+	var listing models.Listing
+
+	req, _ = http.NewRequest("GET", "/listings/"+newListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+	require.True(t, listing.Hidden)
+
+	moderate("listings", newListing.ID.String(), models.ActionRestore)
+
+	req, _ = http.NewRequest("GET", "/listings/"+newListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var comments models.Page[models.Comment]
+
+	moderate("comments", comment.ID, models.ActionHide)
+
+	req, _ = http.NewRequest("GET", "/listings/"+newListing.ID.String()+"/comments?limit=100", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&comments))
+	for _, item := range comments.Items {
+		require.NotEqual(t, comment.ID, item.ID)
+	}
+
+	moderate("comments", comment.ID, models.ActionRestore)
+
+	var audit models.Page[models.AuditEntry]
+
+	req, _ = http.NewRequest("GET", "/moderation/audit", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&audit))
+	require.GreaterOrEqual(t, audit.Total, 4)
+	require.Equal(t, models.ActionRestore, audit.Items[0].Action)
+	require.Equal(t, otherUser.ID.String(), audit.Items[0].ModeratorID)
+
+	req, _ = http.NewRequest("POST", "/moderation/listings/"+newListing.ID.String()+"/shout", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestContentFilter(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	jsonInput, err := json.Marshal(models.Comment{Comment: "Buy cheap pans"})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var spam models.Comment
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&spam))
+
+	req, _ = http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+newListing.ID.String()+"/comments/"+spam.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	links := models.Listing{
+		Title:       "Links",
+		Description: "https://a.example https://b.example https://c.example https://d.example",
+		Visibility:  models.Public,
+	}
+	jsonInput, err = json.Marshal(links)
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&links))
+
+	require.NoError(t, controllers.GetFirebaseDatabase().FirebaseConnect())
+	storage := controllers.NewStorage()
+	require.NoError(t, storage.SetModerator(otherUser.ID.String(), true))
+	defer storage.SetModerator(otherUser.ID.String(), false)
+
+	queue, err := storage.GetModerationQueue(otherUser.ID.String())
+	require.NoError(t, err)
+
+	flagged := false
+	for _, item := range queue {
+		if item.ID == links.ID.String() {
+			flagged = true
+			require.Equal(t, 1, item.ReasonCounts[models.ReasonSpam])
+		}
+	}
+	require.True(t, flagged)
+
+	req, _ = http.NewRequest("POST", "/moderation/listings/"+links.ID.String()+"/delete", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestContentFilterOnEdits(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	contentFilter := controllers.GetContentFilter()
+	pipeline, err := filter.New(filter.Config{BlockedWords: []string{"spamword"}})
+	require.NoError(t, err)
+	controllers.SetContentFilter(pipeline)
+	defer controllers.SetContentFilter(contentFilter)
+
+	req, _ := http.NewRequest("PATCH", "/listings/"+newListing.ID.String(), strings.NewReader(`{"title":"Spamword special"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, newListing.ID.String(), testToken))
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+	edit := newListing
+	edit.Description = "Now with spamword"
+	jsonInput, err := json.Marshal(edit)
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest("PUT", "/listings/"+newListing.ID.String(), bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, newListing.ID.String(), testToken))
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+	jsonInput, err = json.Marshal(models.Comment{Comment: "Tasty"})
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var created models.Comment
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&created))
+
+	jsonInput, err = json.Marshal(models.Comment{Comment: "Tasty spamword"})
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest("PATCH", "/listings/"+newListing.ID.String()+"/comments/"+created.ID, bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+	// Text of callers who may not edit the comment is never screened.
+	req, _ = http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", strings.NewReader(`{"comment": "Owner's note"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var note models.Comment
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&note))
+
+	req, _ = http.NewRequest("PATCH", "/listings/"+newListing.ID.String()+"/comments/"+note.ID, bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+newListing.ID.String()+"/comments/"+note.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+newListing.ID.String()+"/comments/"+created.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestContentFilterSkipsPrivateListings(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("POST", "/listings", strings.NewReader(`{"title":"Water","visibility":"private"}`))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var listing models.Listing
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+
+		req, _ = http.NewRequest("DELETE", "/listings/"+listing.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		req.Header.Set("If-Match", getETag(t, r, listing.ID.String(), testToken))
+		response = executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	}
+}
+
+func TestDeleteListingClearsReports(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("POST", "/listings", strings.NewReader(`{"title":"Reported","visibility":"public"}`))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var listing models.Listing
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+
+	req, _ = http.NewRequest("POST", "/listings/"+listing.ID.String()+"/report", strings.NewReader(`{"reason": "spam"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("POST", "/listings/"+listing.ID.String()+"/comment", strings.NewReader(`{"comment": "Reported too"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var comment models.Comment
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&comment))
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+listing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	req.Header.Set("If-Match", getETag(t, r, listing.ID.String(), otherToken))
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	require.NoError(t, controllers.GetFirebaseDatabase().FirebaseConnect())
+	storage := controllers.NewStorage()
+
+	var report map[string]interface{}
+	require.NoError(t, storage.NewRef("reports/listings/"+listing.ID.String()).Get(context.Background(), &report))
+	require.Empty(t, report)
+
+	var owner string
+	require.NoError(t, storage.NewRef("listing-index/"+listing.ID.String()).Get(context.Background(), &owner))
+	require.Empty(t, owner)
+
+	var commentListing string
+	require.NoError(t, storage.NewRef("comment-index/"+comment.ID).Get(context.Background(), &commentListing))
+	require.Empty(t, commentListing)
+}
+
+func TestSetReaction(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var listing models.Listing
+
+	jsonInput, err := json.Marshal(models.Reaction{Type: models.ReactionYum})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("PUT", "/listings/"+newListing.ID.String()+"/reactions/me", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("GET", "/listings/"+newListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+	require.Equal(t, models.ReactionYum, listing.MyReaction)
+	require.Equal(t, 1, listing.ReactionCounts[models.ReactionYum])
+	require.False(t, listing.LikedByMe)
+	require.Empty(t, listing.Reactions)
+}
+
+func TestSetReactionInvalid(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("PUT", "/listings/"+newListing.ID.String()+"/reactions/me", bytes.NewBuffer([]byte(`{"type": "meh"}`)))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestClearReaction(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var listing models.Listing
+
+	req, _ := http.NewRequest("DELETE", "/listings/"+newListing.ID.String()+"/reactions/me", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("GET", "/listings/"+newListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+	require.Empty(t, listing.MyReaction)
+	require.Zero(t, listing.ReactionCounts[models.ReactionYum])
+}
+
+func TestFollowUser(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("PUT", "/users/"+newUser.Handle+"/follow", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("PUT", "/users/"+otherUser.Handle+"/follow", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+
+	req, _ = http.NewRequest("PUT", "/users/nobody/follow", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestGetFollowers(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var page models.Page[models.UserSummary]
+
+	req, _ := http.NewRequest("GET", "/users/"+newUser.Handle+"/followers", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&page))
+	require.Equal(t, 1, page.Total)
+	require.Equal(t, otherUser.Name, page.Items[0].Name)
+
+	req, _ = http.NewRequest("GET", "/users/"+otherUser.Handle+"/following", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&page))
+	require.Equal(t, 1, page.Total)
+	require.Equal(t, newUser.Name, page.Items[0].Name)
+}
+
+func TestFollowersOnlyListing(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var page models.Page[models.Listing]
+
+	jsonInput, err := json.Marshal(followersListing)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &followersListing))
+
+	req, _ = http.NewRequest("GET", "/listings/"+followersListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("GET", "/feed?limit=100", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&page))
+
+	ids := make(map[string]bool)
+	for i, listing := range page.Items {
+		if i > 0 {
+			require.False(t, listing.CreatedAt.After(page.Items[i-1].CreatedAt))
+		}
+		ids[listing.ID.String()] = true
+	}
+	require.True(t, ids[followersListing.ID.String()])
+	require.True(t, ids[newListing.ID.String()])
+}
+
+func TestUnfollowUser(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var page models.Page[models.Listing]
+
+	req, _ := http.NewRequest("DELETE", "/users/"+newUser.Handle+"/follow", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("GET", "/listings/"+followersListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+
+	req, _ = http.NewRequest("GET", "/feed", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&page))
+	require.Zero(t, page.Total)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+followersListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, followersListing.ID.String(), testToken))
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestBlockUser(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var page models.Page[models.UserSummary]
+
+	req, _ := http.NewRequest("PUT", "/listings/"+newListing.ID.String()+"/likes/me", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", strings.NewReader(`{"comment": "Before the block"}`))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var earlier models.Comment
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&earlier))
+
+	req, _ = http.NewRequest("PUT", "/users/"+otherUser.Handle+"/block", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("GET", "/users/me/blocks", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&page))
+	require.Equal(t, 1, page.Total)
+	require.Equal(t, otherUser.Handle, page.Items[0].Handle)
+
+	req, _ = http.NewRequest("PUT", "/listings/"+newListing.ID.String()+"/likes/me", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+newListing.ID.String()+"/likes/me", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	jsonInput, err := json.Marshal(models.Comment{Comment: "Blocked"})
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest("POST", "/listings/"+newListing.ID.String()+"/comment", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+
+	req, _ = http.NewRequest("PATCH", "/listings/"+newListing.ID.String()+"/comments/"+earlier.ID, bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+newListing.ID.String()+"/comments/"+earlier.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("PUT", "/users/"+newUser.Handle+"/block", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/users/"+otherUser.Handle+"/block", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestMuteUser(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	allListings := func() map[string]bool {
+		var listings []models.Listing
+
+		req, _ := http.NewRequest("GET", "/all-listings", nil)
+		req.Header.Set("Authorization", "Bearer "+otherToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&listings))
+
+		ids := make(map[string]bool)
+		for _, listing := range listings {
+			ids[listing.ID.String()] = true
+		}
+		return ids
+	}
+
+	req, _ := http.NewRequest("PUT", "/users/"+newUser.Handle+"/mute", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.False(t, allListings()[newListing.ID.String()])
+
+	req, _ = http.NewRequest("DELETE", "/users/"+newUser.Handle+"/mute", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.True(t, allListings()[newListing.ID.String()])
+}
+
+func TestCreatePrivateListing(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	jsonInput, err := json.Marshal(privateListing)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &privateListing))
+}
+
+func TestGetAllListingsHidesPrivate(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var listings []models.Listing
+
+	req, _ := http.NewRequest("GET", "/all-listings", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listings))
+
+	ids := make(map[string]bool)
+	for _, listing := range listings {
+		require.NotEqual(t, models.Private, listing.Visibility)
+		ids[listing.ID.String()] = true
+	}
+	require.False(t, ids[privateListing.ID.String()])
+	require.True(t, ids[newListing.ID.String()])
+}
+
+func TestLikePrivateListingForbidden(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("PUT", "/listings/"+privateListing.ID.String()+"/likes/me", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+}
+
+func TestCommentPrivateListingForbidden(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	jsonInput, err := json.Marshal(comment)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings/"+privateListing.ID.String()+"/comment", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+}
+
+func TestLikeOwnPrivateListing(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("PUT", "/listings/"+privateListing.ID.String()+"/likes/me", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestGetPrivateListingImageForbidden(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("GET", "/listings/"+privateListing.ID.String()+"/image", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+}
+
+func TestCreateListingInvalidVisibility(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	jsonInput, err := json.Marshal(models.Listing{Title: "Invalid", Visibility: "everyone"})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestListingNutrition(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	listing := models.Listing{
+		Title:      "Chicken and rice",
+		Visibility: models.Private,
+		Type:       models.Dinner,
+		Nutrition:  &models.Nutrition{Kcal: 650, ProteinG: 45, CarbsG: 70, FatG: 18, FiberG: 3, SugarG: 2, SodiumMg: 900},
+	}
+	jsonInput, err := json.Marshal(listing)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+	require.Equal(t, 45.0, listing.Nutrition.ProteinG)
+
+	filtered := func(query string) map[string]bool {
+		var listings []models.Listing
+
+		req, _ := http.NewRequest("GET", "/listings?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&listings))
+
+		ids := make(map[string]bool)
+		for _, listing := range listings {
+			ids[listing.ID.String()] = true
+		}
+		return ids
+	}
+
+	require.True(t, filtered("min_protein_g=30&max_kcal=700")[listing.ID.String()])
+	require.False(t, filtered("max_kcal=500")[listing.ID.String()])
+	require.False(t, filtered("min_kcal=0")[newListing.ID.String()])
+
+	req, _ = http.NewRequest("GET", "/listings?min_kcal=lots", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+
+	for _, nutrition := range []models.Nutrition{{Kcal: -1}, {CarbsG: 10, SugarG: 20}, {FatG: 5000}} {
+		jsonInput, err := json.Marshal(models.Listing{Title: "Invalid", Nutrition: &nutrition})
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+listing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, listing.ID.String(), testToken))
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestListingIngredients(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	listing := models.Listing{
+		Title:      "Pancakes",
+		Visibility: models.Private,
+		Type:       models.Breakfast,
+		Ingredients: []models.Ingredient{
+			{Name: "Flour", Quantity: 2, Unit: "cups"},
+			{Name: "milk", Quantity: 0.3, Unit: "L"},
+			{Name: "Eggs", Quantity: 2, Unit: "pcs"},
+			{Name: "Salt", Quantity: 1.5, Unit: "tsp"},
+			{Name: "Blueberries", Quantity: 0.1, Unit: "kg"},
+			{Name: "Vanilla extract", Quantity: 1, Unit: "tbsp"},
+		},
+	}
+	jsonInput, err := json.Marshal(listing)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+	require.Len(t, listing.Ingredients, 6)
+
+	flour := listing.Ingredients[0]
+	require.Equal(t, models.Cup, flour.Unit)
+	require.InDelta(t, 254.4, *flour.Grams, 0.01)
+
+	milk := listing.Ingredients[1]
+	require.Equal(t, models.Milliliter, milk.Unit)
+	require.InDelta(t, 300, milk.Quantity, 0.01)
+	require.InDelta(t, 309, *milk.Grams, 0.01)
+
+	require.Equal(t, models.Piece, listing.Ingredients[2].Unit)
+	require.InDelta(t, 100, *listing.Ingredients[2].Grams, 0.01)
+
+	salt := listing.Ingredients[3]
+	require.Equal(t, models.Tablespoon, salt.Unit)
+	require.InDelta(t, 0.5, salt.Quantity, 0.01)
+	require.InDelta(t, 9, *salt.Grams, 0.01)
+
+	require.Equal(t, models.Gram, listing.Ingredients[4].Unit)
+	require.InDelta(t, 100, *listing.Ingredients[4].Grams, 0.01)
+
+	require.Nil(t, listing.Ingredients[5].Grams)
+
+	for _, ingredient := range []models.Ingredient{{Name: "", Quantity: 1, Unit: "g"}, {Name: "Sugar", Quantity: 0, Unit: "g"}, {Name: "Sugar", Quantity: 1, Unit: "handful"}, {Name: "Sugar", Quantity: 150, Unit: "kg"}} {
+		jsonInput, err := json.Marshal(models.Listing{Title: "Invalid", Ingredients: []models.Ingredient{ingredient}})
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+listing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, listing.ID.String(), testToken))
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestFoods(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var page models.Page[foods.Food]
+
+	req, _ := http.NewRequest("GET", "/foods?q=white+rice&limit=1", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&page))
+	require.Equal(t, 2, page.Total)
+	require.Len(t, page.Items, 1)
+	require.Contains(t, page.Items[0].Name, "Rice, white")
+
+	var food foods.Food
+
+	req, _ = http.NewRequest("GET", "/foods/egg-raw", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&food))
+	require.Equal(t, 143.0, food.Per100g.Kcal)
+
+	req, _ = http.NewRequest("GET", "/foods/unknown", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
+
+	listing := models.Listing{
+		Title:      "Egg fried rice",
+		Visibility: models.Private,
+		Type:       models.Lunch,
+		Ingredients: []models.Ingredient{
+			{Name: "Cooked rice", Quantity: 200, Unit: models.Gram, FoodID: "rice-white-cooked"},
+			{Name: "Eggs", Quantity: 2, Unit: models.Piece, FoodID: "egg-raw"},
+		},
+	}
+	jsonInput, err := json.Marshal(listing)
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+	require.NotNil(t, listing.Nutrition)
+	require.InDelta(t, 403, listing.Nutrition.Kcal, 0.01)
+	require.InDelta(t, 17.9, listing.Nutrition.ProteinG, 0.01)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+listing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, listing.ID.String(), testToken))
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	jsonInput, err = json.Marshal(models.Listing{
+		Title:       "Unknown food",
+		Ingredients: []models.Ingredient{{Name: "Mystery", Quantity: 1, Unit: models.Gram, FoodID: "unknown"}},
+	})
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestBarcodeLookup(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	products := "barcode,description,brand,category,serving_g,energy_kcal,protein_g,carbohydrate_g,fat_g,sugars_g\n" +
+		"036000291452,Oat bar,Acme,Snacks,40,420,8,60,16,20\n" +
+		"4006381333931,Sparkling water,Acme,Beverages,,0,0,0,0,0\n"
+
+	importProducts := func(body string, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/admin/products", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "text/csv")
+		return executeRequest(req, r)
+	}
+
+	require.Equal(t, http.StatusForbidden, importProducts(products, otherToken).Code)
+	require.Equal(t, http.StatusForbidden, importProducts("not,a,product,file", otherToken).Code)
+
+	storage := controllers.NewStorage()
+	require.NoError(t, storage.SetAdmin(otherUser.ID.String(), true))
+	defer storage.SetAdmin(otherUser.ID.String(), false)
+	defer storage.NewRef("products").Child("0036000291452").Delete(context.Background())
+	defer storage.NewRef("products").Child("4006381333931").Delete(context.Background())
+
+	require.Equal(t, http.StatusBadRequest, importProducts("barcode,description\n036000291453,Bad checksum\n", otherToken).Code)
+	require.Equal(t, http.StatusBadRequest, importProducts("barcode,description,serving_g\n036000291452,Oat bar,NaN\n", otherToken).Code)
+
+	response := importProducts(products, otherToken)
+	require.Equal(t, http.StatusOK, response.Code)
+	require.JSONEq(t, `{"imported":2}`, response.Body.String())
+
+	var product foods.Product
+
+	req, _ := http.NewRequest("GET", "/foods/barcode/036000291452", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&product))
+	require.Equal(t, "0036000291452", product.Barcode)
+	require.Equal(t, "Oat bar", product.Name)
+	require.Equal(t, 420.0, product.Per100g.Kcal)
+	require.NotNil(t, product.PerServing)
+	require.Equal(t, 168.0, product.PerServing.Kcal)
+
+	for code, status := range map[string]int{
+		"4006381333931": http.StatusOK,
+		"4006381333932": http.StatusBadRequest,
+		"40063813":      http.StatusBadRequest,
+		"5901234123457": http.StatusNotFound,
+	} {
+		req, _ := http.NewRequest("GET", "/foods/barcode/"+code, nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, status, response.Code, code)
+	}
+}
+
+func TestNutritionStats(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	at := func(t time.Time) *time.Time {
+		return &t
+	}
+
+	var created []models.Listing
+	for _, listing := range []models.Listing{
+		{Title: "Porridge", Type: models.Breakfast, EatenAt: at(time.Date(2026, 1, 6, 7, 0, 0, 0, time.UTC)), Nutrition: &models.Nutrition{Kcal: 400, ProteinG: 20}},
+		{Title: "Late pasta", Type: models.Dinner, EatenAt: at(time.Date(2026, 1, 7, 0, 30, 0, 0, time.FixedZone("", 2*60*60))), Nutrition: &models.Nutrition{Kcal: 700, ProteinG: 25}},
+	} {
+		jsonInput, err := json.Marshal(listing)
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+		created = append(created, listing)
+	}
+
+	stats := func(query string) models.NutritionSummary {
+		var summary models.NutritionSummary
+
+		req, _ := http.NewRequest("GET", "/stats/"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&summary))
+		return summary
+	}
+
+	daily := stats("daily?date=2026-01-06")
+	require.Equal(t, 1, daily.Meals)
+	require.Equal(t, 400.0, daily.Totals.Kcal)
+	require.Equal(t, 20.0, daily.Totals.ProteinG)
+	require.Nil(t, daily.ByMealType[models.Dinner])
+	require.Empty(t, daily.Days)
+
+	// Meals count on the local date they were eaten on, whatever the time zone asked for.
+	daily = stats("daily?date=2026-01-07&tz=UTC")
+	require.Equal(t, 1, daily.Meals)
+	require.Equal(t, 700.0, daily.Totals.Kcal)
+	require.Equal(t, 1, daily.ByMealType[models.Dinner].Meals)
+
+	weekly := stats("weekly?date=2026-01-08&tz=Europe/Vilnius")
+	require.Equal(t, 2, weekly.Meals)
+	require.Len(t, weekly.Days, 7)
+	require.Equal(t, "2026-01-05", weekly.Days[0].Date)
+	require.Equal(t, 1, weekly.Days[1].Meals)
+	require.Equal(t, 700.0, weekly.Days[2].Totals.Kcal)
+
+	monthly := stats("monthly?date=2026-01-20")
+	require.Equal(t, 2, monthly.Meals)
+	require.Len(t, monthly.Days, 31)
+	require.Equal(t, 400.0, monthly.ByMealType[models.Breakfast].Totals.Kcal)
+
+	for _, query := range []string{"daily?date=06-01-2026", "weekly?tz=Mars/Olympus"} {
+		req, _ := http.NewRequest("GET", "/stats/"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	}
+
+	for _, listing := range created {
+		req, _ := http.NewRequest("DELETE", "/listings/"+listing.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		req.Header.Set("If-Match", getETag(t, r, listing.ID.String(), testToken))
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	}
+}
+
+func TestNutritionGoals(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	setGoals := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/users/me/goals", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return executeRequest(req, r)
+	}
+
+	for _, body := range []string{`{"vitamin_q":{"min":1}}`, `{"kcal":{}}`, `{"kcal":{"min":2000,"max":1000}}`, `{"sugar_g":{"max":-1}}`} {
+		require.Equal(t, http.StatusBadRequest, setGoals(body).Code, body)
+	}
+
+	goals := `{"kcal":{"min":500,"max":1200},"water_ml":{"min":500},"sugar_g":{"max":30}}`
+	response := setGoals(goals)
+	require.Equal(t, http.StatusOK, response.Code)
+	require.JSONEq(t, goals, response.Body.String())
+	defer setGoals(`{}`)
+
+	req, _ := http.NewRequest("GET", "/users/me/goals", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.JSONEq(t, goals, response.Body.String())
+
+	var created []models.Listing
+	for day, nutrition := range []models.Nutrition{
+		{Kcal: 800, CarbsG: 100, SugarG: 10, WaterMl: 600},
+		{Kcal: 900, CarbsG: 100, SugarG: 5, WaterMl: 700},
+		{Kcal: 1000, WaterMl: 100},
+		{Kcal: 600, WaterMl: 500},
+	} {
+		eatenAt := time.Date(2026, 2, day+1, 12, 0, 0, 0, time.UTC)
+		listing := models.Listing{Title: "Day", Type: models.Lunch, EatenAt: &eatenAt, Nutrition: &nutrition}
+		jsonInput, err := json.Marshal(listing)
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+		created = append(created, listing)
+	}
+
+	daily := func(date string) models.NutritionSummary {
+		var summary models.NutritionSummary
+
+		req, _ := http.NewRequest("GET", "/stats/daily?date="+date, nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&summary))
+		return summary
+	}
+
+	summary := daily("2026-02-02")
+	require.Len(t, summary.Goals, 3)
+	require.Equal(t, models.NutrientKcal, summary.Goals[0].Nutrient)
+	for _, goal := range summary.Goals {
+		require.True(t, goal.Met, goal.Nutrient)
+	}
+	require.Equal(t, models.Streaks{Current: 2, Best: 2}, *summary.Streaks)
+
+	summary = daily("2026-02-03")
+	require.False(t, summary.Goals[2].Met)
+	require.Equal(t, 100.0, summary.Goals[2].Value)
+	require.Equal(t, models.Streaks{Current: 2, Best: 2}, *summary.Streaks)
+
+	summary = daily("2026-02-04")
+	require.Equal(t, models.Streaks{Current: 1, Best: 2}, *summary.Streaks)
+
+	for _, listing := range created {
+		req, _ := http.NewRequest("DELETE", "/listings/"+listing.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		req.Header.Set("If-Match", getETag(t, r, listing.ID.String(), testToken))
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	}
+}
+
+func TestTimeZones(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	setTimeZone := func(timeZone string) int {
+		req, _ := http.NewRequest("PATCH", "/users/me", strings.NewReader(`{"time_zone":"`+timeZone+`"}`))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return executeRequest(req, r).Code
+	}
+
+	require.Equal(t, http.StatusBadRequest, setTimeZone("Mars/Olympus"))
+	require.Equal(t, http.StatusBadRequest, setTimeZone("Local"))
+	require.Equal(t, http.StatusOK, setTimeZone("Asia/Tokyo"))
+	defer setTimeZone("")
+
+	createListing := func(body string) (models.Listing, int) {
+		var listing models.Listing
+
+		req, _ := http.NewRequest("POST", "/listings", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		if response.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+		}
+		return listing, response.Code
+	}
+
+	now, code := createListing(`{"title":"Now","type":"snack","created_at":"2000-01-01T00:00:00Z"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.WithinDuration(t, time.Now(), now.CreatedAt, time.Minute)
+	require.NotNil(t, now.EatenAt)
+	_, offset := now.EatenAt.Zone()
+	require.Equal(t, 9*60*60, offset)
+
+	snack, code := createListing(`{"title":"Late snack","type":"snack","eaten_at":"2026-03-02T00:30:00+09:00","nutrition":{"kcal":250}}`)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, snack.EatenAt.Equal(time.Date(2026, 3, 1, 15, 30, 0, 0, time.UTC)))
+
+	_, code = createListing(`{"title":"Tomorrow","eaten_at":"` + time.Now().Add(48*time.Hour).Format(time.RFC3339) + `"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	for _, eatenAt := range []string{"0001-01-01T00:00:00Z", "1999-12-31T23:59:59Z"} {
+		_, code = createListing(`{"title":"Ancient","eaten_at":"` + eatenAt + `"}`)
+		require.Equal(t, http.StatusBadRequest, code, eatenAt)
+	}
+
+	meals := func(query string) int {
+		var summary models.NutritionSummary
+
+		req, _ := http.NewRequest("GET", "/stats/daily?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&summary))
+		return summary.Meals
+	}
+
+	require.Equal(t, 1, meals("date=2026-03-02"))
+	require.Equal(t, 0, meals("date=2026-03-01"))
+	require.Equal(t, 0, meals("date=2026-03-01&tz=UTC"))
+	require.Equal(t, 1, meals("date=2026-03-02&tz=UTC"))
+
+	dinner, code := createListing(`{"title":"Late dinner","type":"dinner","eaten_at":"2026-03-05T23:30:00-05:00"}`)
+	require.Equal(t, http.StatusOK, code)
+
+	// Listings stored before eaten_at existed count on the day they were created on in
+	// the time zone asked for.
+	require.NoError(t, controllers.GetFirebaseDatabase().FirebaseConnect())
+	storage := controllers.NewStorage()
+	legacy := models.Listing{
+		ID:         uuid.New(),
+		Title:      "Legacy dinner",
+		Visibility: models.Private,
+		Type:       models.Dinner,
+		UserID:     newUser.ID.String(),
+		Version:    1,
+		CreatedAt:  time.Date(2026, 3, 10, 4, 30, 0, 0, time.UTC),
+	}
+	require.NoError(t, storage.NewRef("/").Update(context.Background(), map[string]interface{}{
+		"listings/" + legacy.UserID + "/" + legacy.ID.String(): legacy,
+		"listing-index/" + legacy.ID.String():                  legacy.UserID,
+	}))
+
+	require.Equal(t, 1, meals("date=2026-03-05&tz=America/New_York"))
+	require.Equal(t, 0, meals("date=2026-03-06&tz=America/New_York"))
+	require.Equal(t, 1, meals("date=2026-03-09&tz=America/New_York"))
+	require.Equal(t, 0, meals("date=2026-03-10&tz=America/New_York"))
+	require.Equal(t, 1, meals("date=2026-03-10"))
+
+	for _, listing := range []models.Listing{now, snack, dinner, legacy} {
+		req, _ := http.NewRequest("DELETE", "/listings/"+listing.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		req.Header.Set("If-Match", getETag(t, r, listing.ID.String(), testToken))
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	}
+}
+
+func TestMealTypeInference(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var created []models.Listing
+	createListing := func(body string) (models.MealType, int) {
+		var listing models.Listing
+
+		req, _ := http.NewRequest("POST", "/listings", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		response := executeRequest(req, r)
+
+		if response.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+			created = append(created, listing)
+		}
+		return listing.Type, response.Code
+	}
+	eatenAt := func(clock string) string {
+		return `{"title":"Meal","eaten_at":"2026-04-01T` + clock + `:00+02:00"}`
+	}
+
+	_, code := createListing(`{"title":"Brunch","type":"brunch"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+
+	mealType, code := createListing(`{"title":"Cake","type":"dessert","eaten_at":"2026-04-01T08:00:00+02:00"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, models.Dessert, mealType)
+
+	for clock, expected := range map[string]models.MealType{
+		"08:15": models.Breakfast,
+		"12:00": models.Lunch,
+		"19:45": models.Dinner,
+		"23:30": models.Snack,
+	} {
+		mealType, code := createListing(eatenAt(clock))
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, expected, mealType, clock)
+	}
+
+	setWindows := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/users/me/meal-windows", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return executeRequest(req, r)
+	}
+
+	for _, body := range []string{
+		`[{"type":"brunch","start":"10:00","end":"12:00"}]`,
+		`[{"type":"lunch","start":"25:00","end":"12:00"}]`,
+		`[{"type":"lunch","start":"12:00","end":"12:00"}]`,
+		`[{"type":"lunch","start":"12:00","end":"15:00"},{"type":"dinner","start":"14:00","end":"20:00"}]`,
+	} {
+		require.Equal(t, http.StatusBadRequest, setWindows(body).Code, body)
+	}
+
+	windows := `[{"type":"breakfast","start":"09:00","end":"12:00"},{"type":"lunch","start":"12:00","end":"15:00"},{"type":"dinner","start":"22:00","end":"01:00"}]`
+	response := setWindows(windows)
+	require.Equal(t, http.StatusOK, response.Code)
+	require.JSONEq(t, windows, response.Body.String())
+
+	for clock, expected := range map[string]models.MealType{
+		"11:00": models.Breakfast,
+		"00:30": models.Dinner,
+		"16:00": models.Snack,
+	} {
+		mealType, code := createListing(eatenAt(clock))
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, expected, mealType, clock)
+	}
+
+	require.Equal(t, http.StatusOK, setWindows(`[]`).Code)
+
+	req, _ := http.NewRequest("GET", "/users/me/meal-windows", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	var defaults models.MealWindows
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&defaults))
+	require.Equal(t, models.DefaultMealWindows, defaults)
+
+	req, _ = http.NewRequest("PATCH", "/listings/"+created[0].ID.String(), strings.NewReader(`{"type":"supper"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, created[0].ID.String(), testToken))
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+	for _, listing := range created {
+		req, _ := http.NewRequest("DELETE", "/listings/"+listing.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		req.Header.Set("If-Match", getETag(t, r, listing.ID.String(), testToken))
+		response := executeRequest(req, r)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	}
+}
+
+func TestSpecificUsersListing(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	usersListing.SharedWith = []string{otherUser.ID.String()}
+	jsonInput, err := json.Marshal(usersListing)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/listings", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &usersListing))
+
+	req, _ = http.NewRequest("PUT", "/listings/"+usersListing.ID.String()+"/likes/me", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/listings/"+usersListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, usersListing.ID.String(), testToken))
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestGetSharedListingOfOtherUser(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	var listing models.Listing
+
+	req, _ := http.NewRequest("GET", "/listings/"+newListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listing))
+	require.Equal(t, newListing.ID, listing.ID)
+}
+
+func TestGetPrivateListingOfOtherUserForbidden(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("GET", "/listings/"+privateListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+}
+
+func TestDeletePrivateListing(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("DELETE", "/listings/"+privateListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, privateListing.ID.String(), testToken))
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestConcurrentLikesAndComments(t *testing.T) {
+	require.NoError(t, controllers.GetFirebaseDatabase().FirebaseConnect())
+	storage := controllers.NewStorage()
+
+	// One user posting this many comments at once would trip the comment rate limit.
+	contentFilter := controllers.GetContentFilter()
+	controllers.SetContentFilter(nil)
+	defer controllers.SetContentFilter(contentFilter)
+
+	before, err := storage.GetListingByID(newListing.ID.String())
+	require.NoError(t, err)
+
+	const likers = 200
+	const commenters = 100
+
+	var wg sync.WaitGroup
+	errs := make(chan error, likers+commenters)
+
+	for i := 0; i < likers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- storage.SetLike(newListing.ID.String(), fmt.Sprintf("liker%d", i), true)
+		}(i)
+	}
+	for i := 0; i < commenters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := storage.CommentListing(newListing.ID.String(), newUser.ID.String(), models.Comment{
+				Comment:   fmt.Sprintf("Comment %d", i),
+				CreatedAt: time.Now(),
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	after, err := storage.GetListingByID(newListing.ID.String())
+	require.NoError(t, err)
+	require.Equal(t, len(before.Reactions)+likers, len(after.Reactions))
+	require.Equal(t, len(before.Comments)+commenters, len(after.Comments))
+	require.Equal(t, before.Version+likers+commenters, after.Version)
+}
+
+func TestDeleteListing(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("DELETE", "/listings/"+newListing.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("If-Match", getETag(t, r, newListing.ID.String(), testToken))
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, "Listing deleted", response.Body.String())
+}
+
+func TestGetAllListings(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("GET", "/all-listings", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.NotEmpty(t, response.Body.String())
+}
+
+func TestDeleteUserByID(t *testing.T) {
+	r := CreateNewRouter()
+
+	r.MountRoutes()
+
+	req, _ := http.NewRequest("DELETE", "/users/delete/"+newUser.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req, r)
+
+	require.Equal(t, http.StatusForbidden, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/users/delete/"+newUser.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, "User deleted", response.Body.String())
+
+	req, _ = http.NewRequest("DELETE", "/users/delete/"+otherUser.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/users/delete/"+otherUser.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req, r)
+
+	require.Equal(t, http.StatusNotFound, response.Code)
 }
 
 // Random sample data generation
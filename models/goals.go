@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrInvalidGoals = errors.New("invalid goals")
+
+// Goals are the daily targets of a user, keyed by nutrient name. A goal with a minimum
+// is a target to reach, such as protein or water, and a goal with a maximum is a limit
+// to stay under, such as sugar.
+type Goals map[string]Range
+
+// Validate checks that every goal is for a known nutrient and has non-negative bounds,
+// at least one of them, with the minimum not above the maximum.
+func (g Goals) Validate() error {
+	for nutrient, goal := range g {
+		if _, ok := (&Nutrition{}).Value(nutrient); !ok {
+			return ErrInvalidGoals
+		}
+		if goal.Min == nil && goal.Max == nil {
+			return ErrInvalidGoals
+		}
+		for _, bound := range []*float64{goal.Min, goal.Max} {
+			if bound != nil && (math.IsNaN(*bound) || math.IsInf(*bound, 0) || *bound < 0) {
+				return ErrInvalidGoals
+			}
+		}
+		if goal.Min != nil && goal.Max != nil && *goal.Min > *goal.Max {
+			return ErrInvalidGoals
+		}
+	}
+	return nil
+}
+
+// Met reports whether totals are within every goal.
+func (g Goals) Met(totals Nutrition) bool {
+	for nutrient, goal := range g {
+		value, _ := totals.Value(nutrient)
+		if !goal.Contains(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// GoalProgress is the progress of one day towards a goal.
+type GoalProgress struct {
+	Nutrient string   `json:"nutrient"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+	Value    float64  `json:"value"`
+	Met      bool     `json:"met"`
+}
+
+// Progress returns the progress of totals towards each goal, in the order of Nutrients.
+func (g Goals) Progress(totals Nutrition) []GoalProgress {
+	progress := []GoalProgress{}
+	for _, nutrient := range Nutrients {
+		goal, ok := g[nutrient]
+		if !ok {
+			continue
+		}
+		value, _ := totals.Value(nutrient)
+		progress = append(progress, GoalProgress{
+			Nutrient: nutrient,
+			Min:      goal.Min,
+			Max:      goal.Max,
+			Value:    value,
+			Met:      goal.Contains(value),
+		})
+	}
+	return progress
+}
+
+// Streaks counts days on which every goal was met. Current is the number of such
+// days in a row ending on the day of t, or on the day before while the day of t has
+// not met its goals yet. Best is the longest run up to the day of t.
+type Streaks struct {
+	Current int `json:"current"`
+	Best    int `json:"best"`
+}
+
+// NewStreaks counts the streaks of days within goals up to the day of t, in the
+// location of t. Meals count on their MealDate. Days without any listings never count.
+func NewStreaks(goals Goals, t time.Time, listings []*Listing) Streaks {
+	var streaks Streaks
+	if len(goals) == 0 {
+		return streaks
+	}
+
+	days := make(map[string]*Nutrition)
+	first := t
+	for _, listing := range listings {
+		day := listing.MealDate(t.Location())
+		if days[day] == nil {
+			days[day] = &Nutrition{}
+		}
+		if listing.Nutrition != nil {
+			days[day].Add(*listing.Nutrition)
+		}
+		if date, err := time.ParseInLocation(DateLayout, day, t.Location()); err == nil && date.Before(first) {
+			first = date
+		}
+	}
+
+	met := func(day time.Time) bool {
+		totals, ok := days[day.Format(DateLayout)]
+		return ok && goals.Met(totals.Rounded())
+	}
+
+	start, _ := Daily.Bounds(first)
+	end, _ := Daily.Bounds(t)
+	run := 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if met(day) {
+			run++
+			streaks.Best = max(streaks.Best, run)
+		} else {
+			run = 0
+		}
+	}
+
+	streaks.Current = run
+	if !met(end) {
+		for day := end.AddDate(0, 0, -1); met(day); day = day.AddDate(0, 0, -1) {
+			streaks.Current++
+		}
+	}
+	return streaks
+}
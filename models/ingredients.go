@@ -0,0 +1,189 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidIngredient = errors.New("invalid ingredient")
+
+type Unit string
+
+const (
+	Gram       Unit = "g"
+	Milliliter Unit = "ml"
+	Cup        Unit = "cup"
+	Tablespoon Unit = "tbsp"
+	Piece      Unit = "piece"
+)
+
+// Limits of a single ingredient.
+const (
+	MaxIngredients          = 100
+	MaxIngredientNameLength = 100
+	maxQuantity             = 100000
+)
+
+// unitAliases maps accepted spellings of units onto a canonical unit and the factor
+// that converts a quantity in the alias into that unit.
+var unitAliases = map[string]struct {
+	unit   Unit
+	factor float64
+}{
+	"g":           {Gram, 1},
+	"gram":        {Gram, 1},
+	"grams":       {Gram, 1},
+	"kg":          {Gram, 1000},
+	"kilogram":    {Gram, 1000},
+	"kilograms":   {Gram, 1000},
+	"ml":          {Milliliter, 1},
+	"milliliter":  {Milliliter, 1},
+	"milliliters": {Milliliter, 1},
+	"l":           {Milliliter, 1000},
+	"liter":       {Milliliter, 1000},
+	"liters":      {Milliliter, 1000},
+	"cup":         {Cup, 1},
+	"cups":        {Cup, 1},
+	"tbsp":        {Tablespoon, 1},
+	"tablespoon":  {Tablespoon, 1},
+	"tablespoons": {Tablespoon, 1},
+	"tsp":         {Tablespoon, 1.0 / 3},
+	"teaspoon":    {Tablespoon, 1.0 / 3},
+	"teaspoons":   {Tablespoon, 1.0 / 3},
+	"piece":       {Piece, 1},
+	"pieces":      {Piece, 1},
+	"pc":          {Piece, 1},
+	"pcs":         {Piece, 1},
+}
+
+// Milliliters per volume unit.
+var unitMilliliters = map[Unit]float64{
+	Milliliter: 1,
+	Cup:        240,
+	Tablespoon: 15,
+}
+
+// densities are grams per milliliter of common ingredients.
+var densities = map[string]float64{
+	"water":       1,
+	"milk":        1.03,
+	"cream":       1.01,
+	"yogurt":      1.03,
+	"olive oil":   0.91,
+	"oil":         0.92,
+	"butter":      0.91,
+	"honey":       1.42,
+	"sugar":       0.85,
+	"brown sugar": 0.9,
+	"salt":        1.2,
+	"flour":       0.53,
+	"rice":        0.85,
+	"oats":        0.41,
+	"soy sauce":   1.15,
+}
+
+// pieceGrams are typical weights in grams of a single piece of common ingredients.
+var pieceGrams = map[string]float64{
+	"egg":    50,
+	"banana": 118,
+	"apple":  182,
+	"orange": 131,
+	"tomato": 123,
+	"onion":  110,
+	"potato": 173,
+	"carrot": 61,
+	"garlic": 3,
+}
+
+// Ingredient is one ingredient of a meal, optionally linked to an entry of the food
+// database. Grams is filled in by the server when the quantity can be converted into
+// grams.
+type Ingredient struct {
+	Name     string   `json:"name"`
+	Quantity float64  `json:"quantity"`
+	Unit     Unit     `json:"unit"`
+	FoodID   string   `json:"food_id,omitempty"`
+	Grams    *float64 `json:"grams,omitempty"`
+}
+
+// ParseUnit returns the canonical unit for a spelling of a unit and the factor that
+// converts quantities in that spelling into the canonical unit.
+func ParseUnit(unit string) (Unit, float64, bool) {
+	alias, ok := unitAliases[strings.ToLower(strings.TrimSpace(unit))]
+	return alias.unit, alias.factor, ok
+}
+
+// Validate checks the name, quantity and unit of the ingredient. The quantity limit
+// applies in the canonical unit, so a normalized ingredient stays valid.
+func (i *Ingredient) Validate() error {
+	name := strings.TrimSpace(i.Name)
+	if name == "" || utf8.RuneCountInString(name) > MaxIngredientNameLength {
+		return ErrInvalidIngredient
+	}
+	_, factor, ok := ParseUnit(string(i.Unit))
+	if !ok {
+		return ErrInvalidIngredient
+	}
+	if math.IsNaN(i.Quantity) || i.Quantity <= 0 || i.Quantity*factor > maxQuantity {
+		return ErrInvalidIngredient
+	}
+	return nil
+}
+
+// Normalize rewrites the ingredient in its canonical unit and fills in its weight in
+// grams where it is known. The ingredient must be valid.
+func (i *Ingredient) Normalize() {
+	unit, factor, _ := ParseUnit(string(i.Unit))
+	i.Name = strings.TrimSpace(i.Name)
+	i.Unit = unit
+	i.Quantity *= factor
+	i.Grams = nil
+	if grams, ok := i.ToGrams(); ok {
+		i.Grams = &grams
+	}
+}
+
+// ToGrams converts the quantity of the ingredient, in its canonical unit, into grams.
+// Volumes need the density and pieces the typical weight of the ingredient.
+func (i *Ingredient) ToGrams() (float64, bool) {
+	switch i.Unit {
+	case Gram:
+		return i.Quantity, true
+	case Piece:
+		weight, ok := lookupIngredient(pieceGrams, i.Name)
+		return i.Quantity * weight, ok
+	}
+
+	milliliters, ok := unitMilliliters[i.Unit]
+	if !ok {
+		return 0, false
+	}
+	density, ok := lookupIngredient(densities, i.Name)
+	return i.Quantity * milliliters * density, ok
+}
+
+// lookupIngredient finds name in table, ignoring case and a plural "s" or "es".
+func lookupIngredient(table map[string]float64, name string) (float64, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, candidate := range []string{name, strings.TrimSuffix(name, "s"), strings.TrimSuffix(name, "es")} {
+		if value, ok := table[candidate]; ok {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// NormalizeIngredients returns normalized copies of valid ingredients.
+func NormalizeIngredients(ingredients []Ingredient) []Ingredient {
+	if len(ingredients) == 0 {
+		return nil
+	}
+	normalized := make([]Ingredient, len(ingredients))
+	for index, ingredient := range ingredients {
+		ingredient.Normalize()
+		normalized[index] = ingredient
+	}
+	return normalized
+}
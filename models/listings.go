@@ -1,8 +1,15 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Ygnas/FoodLog/util"
 	"github.com/google/uuid"
 )
 
@@ -16,14 +23,66 @@ const (
 	Dessert   MealType = "dessert"
 )
 
+var (
+	ErrInvalidListing = errors.New("invalid listing")
+	ErrInvalidComment = errors.New("invalid comment")
+)
+
+type Visibility string
+
+const (
+	Private   Visibility = "private"
+	Followers Visibility = "followers"
+	Users     Visibility = "users"
+	Public    Visibility = "public"
+)
+
+// Valid reports whether v is one of the known visibility levels.
+func (v Visibility) Valid() bool {
+	switch v {
+	case Private, Followers, Users, Public:
+		return true
+	}
+	return false
+}
+
+// MaxCommentLength is the longest comment accepted, in characters.
+const MaxCommentLength = 1000
+
 type Comment struct {
-	Email     string    `json:"email"`
+	ID        string    `json:"id"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
 	Comment   string    `json:"comment"`
+	Replies   []Comment `json:"replies,omitempty"`
+	Hidden    bool      `json:"hidden,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Validate checks that the comment text is neither blank nor too long.
+func (c *Comment) Validate() error {
+	text := strings.TrimSpace(c.Comment)
+	if text == "" || utf8.RuneCountInString(text) > MaxCommentLength {
+		return ErrInvalidComment
+	}
+	return nil
+}
+
+// Like is a liker of a listing as returned by the likes endpoint. Likes are stored as
+// reactions.
 type Like struct {
-	Email string `json:"email"`
+	UserID    string    `json:"user_id"`
+	Handle    string    `json:"handle,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// legacyLike is the layout of likes stored before reactions existed.
+type legacyLike struct {
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Location struct {
@@ -32,16 +91,279 @@ type Location struct {
 }
 
 type Listing struct {
-	ID          uuid.UUID `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Shared      bool      `json:"shared"`
-	Image       string    `json:"image"`
-	Type        MealType  `json:"type"`
-	Likes       []Like    `json:"likes"`
-	Location    Location  `json:"location"`
-	Comments    []Comment `json:"comments"`
-	UserEmail   string    `json:"user_email"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uuid.UUID            `json:"id"`
+	Title          string               `json:"title"`
+	Description    string               `json:"description"`
+	Visibility     Visibility           `json:"visibility"`
+	SharedWith     []string             `json:"shared_with,omitempty"`
+	Image          string               `json:"image"`
+	Type           MealType             `json:"type"`
+	EatenAt        *time.Time           `json:"eaten_at,omitempty"`
+	Nutrition      *Nutrition           `json:"nutrition,omitempty"`
+	Ingredients    []Ingredient         `json:"ingredients,omitempty"`
+	Reactions      map[string]Reaction  `json:"reactions,omitempty"`
+	ReactionCounts map[ReactionType]int `json:"reaction_counts,omitempty"`
+	MyReaction     ReactionType         `json:"my_reaction,omitempty"`
+	LikeCount      int                  `json:"like_count,omitempty"`
+	LikedByMe      bool                 `json:"liked_by_me,omitempty"`
+	Location       Location             `json:"location"`
+	Comments       map[string]Comment   `json:"comments,omitempty"`
+	CommentCount   int                  `json:"comment_count,omitempty"`
+	UserID         string               `json:"user_id"`
+	UserHandle     string               `json:"user_handle"`
+	Version        int64                `json:"version"`
+	Hidden         bool                 `json:"hidden,omitempty"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+
+	legacyLikes bool
+}
+
+// UnmarshalJSON decodes a listing, mapping the legacy "shared" flag of listings
+// stored before visibility levels existed onto Public or Private, likes stored before
+// reactions existed onto like reactions keyed by the email hash of the liker until
+// users are rekeyed by ID, and comments stored as arrays onto maps
+// keyed by their array index.
+func (l *Listing) UnmarshalJSON(data []byte) error {
+	type listing Listing
+	aux := struct {
+		*listing
+		Shared   *bool           `json:"shared"`
+		Likes    json.RawMessage `json:"likes"`
+		Comments json.RawMessage `json:"comments"`
+	}{listing: (*listing)(l)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	likes, err := decodeKeyed[legacyLike](aux.Likes)
+	if err != nil {
+		return err
+	}
+	l.legacyLikes = len(likes) > 0
+	for _, like := range likes {
+		key := util.Base64Encode(like.Email)
+		if _, ok := l.Reactions[key]; ok {
+			continue
+		}
+		if l.Reactions == nil {
+			l.Reactions = make(map[string]Reaction)
+		}
+		l.Reactions[key] = Reaction{Type: ReactionLike, CreatedAt: like.CreatedAt}
+	}
+
+	if l.Comments, err = decodeKeyed[Comment](aux.Comments); err != nil {
+		return err
+	}
+
+	if l.Visibility == "" && aux.Shared != nil {
+		if *aux.Shared {
+			l.Visibility = Public
+		} else {
+			l.Visibility = Private
+		}
+	}
+	return nil
+}
+
+// Thread returns the top-level comments of the listing oldest first, each with its
+// replies attached. Comments hidden by moderators are left out. Comments stored before
+// they had IDs get their key as ID.
+func (l *Listing) Thread() []Comment {
+	replies := make(map[string][]Comment)
+	var thread []Comment
+
+	for key, comment := range l.Comments {
+		if comment.Hidden {
+			continue
+		}
+		if comment.ID == "" {
+			comment.ID = key
+		}
+		if comment.ParentID != "" {
+			replies[comment.ParentID] = append(replies[comment.ParentID], comment)
+			continue
+		}
+		thread = append(thread, comment)
+	}
+
+	sortComments(thread)
+	for i := range thread {
+		thread[i].Replies = replies[thread[i].ID]
+		sortComments(thread[i].Replies)
+	}
+	return thread
+}
+
+func sortComments(comments []Comment) {
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+}
+
+// decodeKeyed decodes a Firebase collection that is either an object keyed by id or,
+// for data written before collections were keyed, an array.
+func decodeKeyed[T any](data json.RawMessage) (map[string]T, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	if data[0] == '[' {
+		var items []*T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		keyed := make(map[string]T, len(items))
+		for index, item := range items {
+			if item != nil {
+				keyed[strconv.Itoa(index)] = *item
+			}
+		}
+		return keyed, nil
+	}
+
+	var keyed map[string]T
+	if err := json.Unmarshal(data, &keyed); err != nil {
+		return nil, err
+	}
+	return keyed, nil
+}
+
+// VisibleTo reports whether the listing can be seen by the user with the given ID.
+// Owners can always see their listings. Followers-only listings need the follow graph,
+// so they are treated as private here and resolved by the storage layer.
+func (l *Listing) VisibleTo(userID string) bool {
+	if l.UserID == userID {
+		return true
+	}
+	if l.HiddenFrom(userID) {
+		return false
+	}
+
+	switch l.Visibility {
+	case Public:
+		return true
+	case Users:
+		for _, shared := range l.SharedWith {
+			if shared == userID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HiddenFrom reports whether the listing was hidden by moderators from the user with
+// the given ID. Owners still see their hidden listings.
+func (l *Listing) HiddenFrom(userID string) bool {
+	return l.Hidden && l.UserID != userID
+}
+
+// HasLegacyLikes reports whether the stored listing still holds likes from before
+// reactions existed.
+func (l *Listing) HasLegacyLikes() bool {
+	return l.legacyLikes
+}
+
+// Summarize prepares the listing for a response to the user with the given ID,
+// replacing the reactions with their counts and that user's own reaction, and the
+// comments with their count.
+func (l *Listing) Summarize(userID string) {
+	l.CommentCount = 0
+	for _, comment := range l.Comments {
+		if !comment.Hidden {
+			l.CommentCount++
+		}
+	}
+	l.Comments = nil
+
+	l.ReactionCounts = make(map[ReactionType]int)
+	l.MyReaction = ""
+	for _, reaction := range l.Reactions {
+		l.ReactionCounts[reaction.Type]++
+		if reaction.UserID == userID {
+			l.MyReaction = reaction.Type
+		}
+	}
+	l.LikeCount = l.ReactionCounts[ReactionLike]
+	l.LikedByMe = l.MyReaction == ReactionLike
+	l.Reactions = nil
+}
+
+// ClearSummary removes the fields Summarize fills in. They only belong in responses,
+// so they are cleared before a listing is stored.
+func (l *Listing) ClearSummary() {
+	l.ReactionCounts = nil
+	l.MyReaction = ""
+	l.LikeCount = 0
+	l.LikedByMe = false
+	l.CommentCount = 0
+}
+
+// maxClockSkew is how far in the future the time a meal was eaten may be, to allow for
+// clients with clocks running ahead.
+const maxClockSkew = time.Hour
+
+// minEatenAt is the earliest time a meal may have been eaten at. Summaries and streaks
+// walk the days from the earliest meal, so they must not start centuries ago.
+var minEatenAt = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// MealDate returns the date the meal was eaten on in the DateLayout format: the local
+// date of eaten_at in the offset it was given with, which meal type inference uses
+// too. Listings stored before eaten_at existed fall back to the date they were created
+// on in loc.
+func (l *Listing) MealDate(loc *time.Location) string {
+	if l.EatenAt != nil {
+		return l.EatenAt.Format(DateLayout)
+	}
+	return l.CreatedAt.In(loc).Format(DateLayout)
+}
+
+// Validate checks the user-editable fields of the listing.
+func (l *Listing) Validate() error {
+	if !l.Visibility.Valid() {
+		return ErrInvalidListing
+	}
+	if l.Type != "" && !l.Type.Valid() {
+		return ErrUnknownMealType
+	}
+	if l.EatenAt != nil && (l.EatenAt.Before(minEatenAt) || l.EatenAt.After(time.Now().Add(maxClockSkew))) {
+		return ErrInvalidListing
+	}
+	if l.Nutrition != nil {
+		if err := l.Nutrition.Validate(); err != nil {
+			return err
+		}
+	}
+	if len(l.Ingredients) > MaxIngredients {
+		return ErrInvalidIngredient
+	}
+	for i := range l.Ingredients {
+		if err := l.Ingredients[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyEdits copies the user-editable fields of edit onto the listing, normalizing its
+// ingredients. The meal type and the time the meal was eaten are kept when edit has
+// none. Server-owned fields such as the ID, owner, likes, comments and timestamps are
+// left untouched.
+func (l *Listing) ApplyEdits(edit *Listing) {
+	l.Title = edit.Title
+	l.Description = edit.Description
+	if edit.Type != "" {
+		l.Type = edit.Type
+	}
+	l.Nutrition = edit.Nutrition
+	l.Ingredients = NormalizeIngredients(edit.Ingredients)
+	if edit.EatenAt != nil {
+		l.EatenAt = edit.EatenAt
+	}
+	l.Visibility = edit.Visibility
+	l.SharedWith = edit.SharedWith
+	l.Location = edit.Location
+	l.Image = edit.Image
 }
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnknownMealType    = errors.New("unknown meal type")
+	ErrInvalidMealWindows = errors.New("invalid meal windows")
+)
+
+// Valid reports whether m is one of the known meal types.
+func (m MealType) Valid() bool {
+	switch m {
+	case Breakfast, Lunch, Dinner, Snack, Dessert:
+		return true
+	}
+	return false
+}
+
+// clockLayout is the layout of the start and end of meal windows.
+const clockLayout = "15:04"
+
+const minutesPerDay = 24 * 60
+
+// MealWindow is the local time of day a meal type is usually eaten at, from Start up
+// to but not including End, such as "11:30" to "14:00". Windows ending before they
+// start run past midnight.
+type MealWindow struct {
+	Type  MealType `json:"type"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+func (w *MealWindow) minutes() (int, int, bool) {
+	start, err := time.Parse(clockLayout, w.Start)
+	if err != nil {
+		return 0, 0, false
+	}
+	end, err := time.Parse(clockLayout, w.End)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), true
+}
+
+// contains reports whether the window contains minute, counted from midnight.
+func (w *MealWindow) contains(minute int) bool {
+	start, end, ok := w.minutes()
+	if !ok {
+		return false
+	}
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// MealWindows are the windows meal types of listings without one are inferred from.
+type MealWindows []MealWindow
+
+// DefaultMealWindows are used for users who have not configured their own.
+var DefaultMealWindows = MealWindows{
+	{Type: Breakfast, Start: "05:00", End: "10:30"},
+	{Type: Lunch, Start: "11:00", End: "14:30"},
+	{Type: Dinner, Start: "17:00", End: "21:30"},
+}
+
+// Validate checks that every window has a known meal type and valid, distinct start
+// and end times, and that no two windows overlap.
+func (w MealWindows) Validate() error {
+	var taken [minutesPerDay]bool
+	for i := range w {
+		window := &w[i]
+		start, end, ok := window.minutes()
+		if !ok || start == end || !window.Type.Valid() {
+			return ErrInvalidMealWindows
+		}
+		for minute := start; minute != end; minute = (minute + 1) % minutesPerDay {
+			if taken[minute] {
+				return ErrInvalidMealWindows
+			}
+			taken[minute] = true
+		}
+	}
+	return nil
+}
+
+// Infer returns the meal type of the first window containing the local time of day of
+// t, or Snack outside every window. Empty windows fall back to DefaultMealWindows.
+func (w MealWindows) Infer(t time.Time) MealType {
+	if len(w) == 0 {
+		w = DefaultMealWindows
+	}
+	minute := t.Hour()*60 + t.Minute()
+	for i := range w {
+		if w[i].contains(minute) {
+			return w[i].Type
+		}
+	}
+	return Snack
+}
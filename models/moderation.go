@@ -0,0 +1,130 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidReport = errors.New("invalid report")
+	ErrInvalidAction = errors.New("invalid moderation action")
+)
+
+type ReportReason string
+
+const (
+	ReasonSpam          ReportReason = "spam"
+	ReasonInappropriate ReportReason = "inappropriate"
+	ReasonHarassment    ReportReason = "harassment"
+	ReasonOther         ReportReason = "other"
+)
+
+// Valid reports whether r is one of the known report reasons.
+func (r ReportReason) Valid() bool {
+	switch r {
+	case ReasonSpam, ReasonInappropriate, ReasonHarassment, ReasonOther:
+		return true
+	}
+	return false
+}
+
+// MaxReportNoteLength is the longest note accepted with a report, in bytes.
+const MaxReportNoteLength = 500
+
+// Report is a single user's report of a listing or comment.
+type Report struct {
+	UserID    string       `json:"user_id"`
+	Reason    ReportReason `json:"reason"`
+	Note      string       `json:"note,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Validate checks the reason and note of the report.
+func (r *Report) Validate() error {
+	if !r.Reason.Valid() || len(r.Note) > MaxReportNoteLength {
+		return ErrInvalidReport
+	}
+	return nil
+}
+
+type ContentKind string
+
+const (
+	KindListing ContentKind = "listings"
+	KindComment ContentKind = "comments"
+)
+
+// Valid reports whether k is a kind of content that can be reported.
+func (k ContentKind) Valid() bool {
+	return k == KindListing || k == KindComment
+}
+
+type ModerationAction string
+
+const (
+	ActionHide    ModerationAction = "hide"
+	ActionRestore ModerationAction = "restore"
+	ActionDelete  ModerationAction = "delete"
+)
+
+// Valid reports whether a is one of the supported moderation actions.
+func (a ModerationAction) Valid() bool {
+	switch a {
+	case ActionHide, ActionRestore, ActionDelete:
+		return true
+	}
+	return false
+}
+
+// ReportedItem groups the open reports of one listing or comment.
+type ReportedItem struct {
+	Kind      ContentKind       `json:"kind"`
+	ID        string            `json:"id"`
+	ListingID string            `json:"listing_id"`
+	Reports   map[string]Report `json:"reports,omitempty"`
+}
+
+// ModerationItem is an entry of the moderation queue: a reported listing or comment
+// with its report counts.
+type ModerationItem struct {
+	Kind           ContentKind          `json:"kind"`
+	ID             string               `json:"id"`
+	ListingID      string               `json:"listing_id"`
+	Preview        string               `json:"preview"`
+	Hidden         bool                 `json:"hidden"`
+	ReportCount    int                  `json:"report_count"`
+	ReasonCounts   map[ReportReason]int `json:"reason_counts"`
+	LastReportedAt time.Time            `json:"last_reported_at"`
+}
+
+// NewModerationItem summarizes the reports of item.
+func NewModerationItem(item *ReportedItem) ModerationItem {
+	summary := ModerationItem{
+		Kind:         item.Kind,
+		ID:           item.ID,
+		ListingID:    item.ListingID,
+		ReportCount:  len(item.Reports),
+		ReasonCounts: make(map[ReportReason]int),
+	}
+	for _, report := range item.Reports {
+		summary.ReasonCounts[report.Reason]++
+		if report.CreatedAt.After(summary.LastReportedAt) {
+			summary.LastReportedAt = report.CreatedAt
+		}
+	}
+	return summary
+}
+
+// AuditEntry records a moderation action taken on a listing or comment.
+type AuditEntry struct {
+	ID          string           `json:"id"`
+	ModeratorID string           `json:"moderator_id"`
+	Action      ModerationAction `json:"action"`
+	Kind        ContentKind      `json:"kind"`
+	TargetID    string           `json:"target_id"`
+	ListingID   string           `json:"listing_id"`
+	OwnerID     string           `json:"owner_id"`
+	ReportCount int              `json:"report_count"`
+	Note        string           `json:"note,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
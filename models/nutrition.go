@@ -0,0 +1,139 @@
+package models
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrInvalidNutrition = errors.New("invalid nutrition")
+
+// Nutrient names, as used in JSON and in listing query parameters.
+const (
+	NutrientKcal    = "kcal"
+	NutrientProtein = "protein_g"
+	NutrientCarbs   = "carbs_g"
+	NutrientFat     = "fat_g"
+	NutrientFiber   = "fiber_g"
+	NutrientSugar   = "sugar_g"
+	NutrientSodium  = "sodium_mg"
+	NutrientWater   = "water_ml"
+)
+
+// Nutrients lists every nutrient a listing can record.
+var Nutrients = []string{
+	NutrientKcal, NutrientProtein, NutrientCarbs, NutrientFat, NutrientFiber, NutrientSugar, NutrientSodium,
+	NutrientWater,
+}
+
+// Upper bounds of plausible values for a single meal.
+const (
+	maxKcal   = 10000
+	maxGrams  = 2000
+	maxSodium = 100000
+	maxWater  = 10000
+)
+
+// Nutrition is the energy, macronutrients and water of a meal. Energy is in
+// kilocalories, sodium in milligrams, water in milliliters and everything else in
+// grams.
+type Nutrition struct {
+	Kcal     float64 `json:"kcal"`
+	ProteinG float64 `json:"protein_g"`
+	CarbsG   float64 `json:"carbs_g"`
+	FatG     float64 `json:"fat_g"`
+	FiberG   float64 `json:"fiber_g"`
+	SugarG   float64 `json:"sugar_g"`
+	SodiumMg float64 `json:"sodium_mg"`
+	WaterMl  float64 `json:"water_ml"`
+}
+
+// Value returns the amount of the named nutrient.
+func (n *Nutrition) Value(nutrient string) (float64, bool) {
+	switch nutrient {
+	case NutrientKcal:
+		return n.Kcal, true
+	case NutrientProtein:
+		return n.ProteinG, true
+	case NutrientCarbs:
+		return n.CarbsG, true
+	case NutrientFat:
+		return n.FatG, true
+	case NutrientFiber:
+		return n.FiberG, true
+	case NutrientSugar:
+		return n.SugarG, true
+	case NutrientSodium:
+		return n.SodiumMg, true
+	case NutrientWater:
+		return n.WaterMl, true
+	}
+	return 0, false
+}
+
+// Validate checks that every amount is a non-negative number within plausible bounds
+// and that sugar does not exceed carbohydrates.
+func (n *Nutrition) Validate() error {
+	for _, nutrient := range Nutrients {
+		value, _ := n.Value(nutrient)
+		limit := float64(maxGrams)
+		switch nutrient {
+		case NutrientKcal:
+			limit = maxKcal
+		case NutrientSodium:
+			limit = maxSodium
+		case NutrientWater:
+			limit = maxWater
+		}
+		if math.IsNaN(value) || value < 0 || value > limit {
+			return ErrInvalidNutrition
+		}
+	}
+	if n.SugarG > n.CarbsG {
+		return ErrInvalidNutrition
+	}
+	return nil
+}
+
+// Scaled returns the nutrition multiplied by factor, such as the nutrition of 250 g
+// of a food out of its nutrition per 100 g with a factor of 2.5.
+func (n Nutrition) Scaled(factor float64) Nutrition {
+	return Nutrition{
+		Kcal:     n.Kcal * factor,
+		ProteinG: n.ProteinG * factor,
+		CarbsG:   n.CarbsG * factor,
+		FatG:     n.FatG * factor,
+		FiberG:   n.FiberG * factor,
+		SugarG:   n.SugarG * factor,
+		SodiumMg: n.SodiumMg * factor,
+		WaterMl:  n.WaterMl * factor,
+	}
+}
+
+// Add adds the amounts of other to the nutrition.
+func (n *Nutrition) Add(other Nutrition) {
+	n.Kcal += other.Kcal
+	n.ProteinG += other.ProteinG
+	n.CarbsG += other.CarbsG
+	n.FatG += other.FatG
+	n.FiberG += other.FiberG
+	n.SugarG += other.SugarG
+	n.SodiumMg += other.SodiumMg
+	n.WaterMl += other.WaterMl
+}
+
+// Rounded returns the nutrition with every amount rounded to one decimal place.
+func (n Nutrition) Rounded() Nutrition {
+	round := func(value float64) float64 {
+		return math.Round(value*10) / 10
+	}
+	return Nutrition{
+		Kcal:     round(n.Kcal),
+		ProteinG: round(n.ProteinG),
+		CarbsG:   round(n.CarbsG),
+		FatG:     round(n.FatG),
+		FiberG:   round(n.FiberG),
+		SugarG:   round(n.SugarG),
+		SodiumMg: round(n.SodiumMg),
+		WaterMl:  round(n.WaterMl),
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+// Page is one page of a paginated collection.
+type Page[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// NewPage cuts the page starting at offset with at most limit items out of items.
+func NewPage[T any](items []T, limit int, offset int) Page[T] {
+	page := Page[T]{Items: []T{}, Total: len(items), Limit: limit, Offset: offset}
+	if offset >= len(items) {
+		return page
+	}
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	page.Items = items[offset:end]
+	return page
+}
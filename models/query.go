@@ -0,0 +1,44 @@
+package models
+
+// Range bounds a value. A nil bound is open.
+type Range struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// Contains reports whether value lies within the range, bounds included.
+func (r Range) Contains(value float64) bool {
+	return (r.Min == nil || value >= *r.Min) && (r.Max == nil || value <= *r.Max)
+}
+
+// ListingQuery filters listings returned by listing queries.
+type ListingQuery struct {
+	// Nutrients bounds nutrient amounts by nutrient name. Listings without nutrition
+	// never match a query with nutrient bounds.
+	Nutrients map[string]Range
+}
+
+// Matches reports whether listing satisfies the query.
+func (q *ListingQuery) Matches(listing *Listing) bool {
+	if len(q.Nutrients) > 0 && listing.Nutrition == nil {
+		return false
+	}
+	for nutrient, bounds := range q.Nutrients {
+		value, ok := listing.Nutrition.Value(nutrient)
+		if !ok || !bounds.Contains(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the listings matching the query, keeping their order.
+func (q *ListingQuery) Filter(listings []*Listing) []*Listing {
+	matching := make([]*Listing, 0, len(listings))
+	for _, listing := range listings {
+		if q.Matches(listing) {
+			matching = append(matching, listing)
+		}
+	}
+	return matching
+}
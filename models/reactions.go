@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidReaction = errors.New("invalid reaction")
+
+type ReactionType string
+
+const (
+	ReactionLike       ReactionType = "like"
+	ReactionYum        ReactionType = "yum"
+	ReactionHealthy    ReactionType = "healthy"
+	ReactionWantRecipe ReactionType = "want-recipe"
+)
+
+// Valid reports whether r is one of the supported reactions.
+func (r ReactionType) Valid() bool {
+	switch r {
+	case ReactionLike, ReactionYum, ReactionHealthy, ReactionWantRecipe:
+		return true
+	}
+	return false
+}
+
+// Reaction is a user's single reaction to a listing. Likes are reactions of type
+// ReactionLike.
+type Reaction struct {
+	UserID    string       `json:"user_id"`
+	Type      ReactionType `json:"type"`
+	CreatedAt time.Time    `json:"created_at"`
+}
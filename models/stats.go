@@ -0,0 +1,119 @@
+package models
+
+import "time"
+
+// Period is the length of time a nutrition summary covers.
+type Period string
+
+const (
+	Daily   Period = "daily"
+	Weekly  Period = "weekly"
+	Monthly Period = "monthly"
+)
+
+// DateLayout is the layout of dates in summaries and their query parameters.
+const DateLayout = "2006-01-02"
+
+// Bounds returns the start and end of the period containing t, in the location of t.
+// Weeks start on Monday.
+func (p Period) Bounds(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch p {
+	case Weekly:
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7)
+	case Monthly:
+		start = start.AddDate(0, 0, 1-start.Day())
+		return start, start.AddDate(0, 1, 0)
+	}
+	return start, start.AddDate(0, 0, 1)
+}
+
+// MealSummary is the number of meals in a group and their total nutrition. Meals
+// without nutrition count as meals but add nothing to the totals.
+type MealSummary struct {
+	Meals  int       `json:"meals"`
+	Totals Nutrition `json:"totals"`
+}
+
+func (m *MealSummary) add(listing *Listing) {
+	m.Meals++
+	if listing.Nutrition != nil {
+		m.Totals.Add(*listing.Nutrition)
+	}
+}
+
+// DaySummary is the summary of one day of a weekly or monthly summary.
+type DaySummary struct {
+	Date string `json:"date"`
+	MealSummary
+}
+
+// NutritionSummary aggregates the listings of a user eaten within a period. Only
+// listings with a meal type are broken down by meal type. Daily summaries of users
+// with goals include their progress and streaks.
+type NutritionSummary struct {
+	Period     Period                    `json:"period"`
+	Start      time.Time                 `json:"start"`
+	End        time.Time                 `json:"end"`
+	TimeZone   string                    `json:"time_zone"`
+	Meals      int                       `json:"meals"`
+	Totals     Nutrition                 `json:"totals"`
+	ByMealType map[MealType]*MealSummary `json:"by_meal_type"`
+	Days       []DaySummary              `json:"days,omitempty"`
+	Goals      []GoalProgress            `json:"goals,omitempty"`
+	Streaks    *Streaks                  `json:"streaks,omitempty"`
+}
+
+// NewNutritionSummary summarizes the listings of meals eaten within the period
+// containing t, in the location of t. Meals count on their MealDate in that location.
+func NewNutritionSummary(period Period, t time.Time, listings []*Listing) NutritionSummary {
+	start, end := period.Bounds(t)
+	summary := NutritionSummary{
+		Period:     period,
+		Start:      start,
+		End:        end,
+		TimeZone:   t.Location().String(),
+		ByMealType: make(map[MealType]*MealSummary),
+	}
+
+	days := make(map[string]*MealSummary)
+	if period != Daily {
+		for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+			summary.Days = append(summary.Days, DaySummary{Date: day.Format(DateLayout)})
+		}
+		for i := range summary.Days {
+			days[summary.Days[i].Date] = &summary.Days[i].MealSummary
+		}
+	}
+
+	first, last := start.Format(DateLayout), end.Format(DateLayout)
+	all := MealSummary{}
+	for _, listing := range listings {
+		date := listing.MealDate(t.Location())
+		if date < first || date >= last {
+			continue
+		}
+
+		all.add(listing)
+		if listing.Type != "" {
+			if summary.ByMealType[listing.Type] == nil {
+				summary.ByMealType[listing.Type] = &MealSummary{}
+			}
+			summary.ByMealType[listing.Type].add(listing)
+		}
+		if day, ok := days[date]; ok {
+			day.add(listing)
+		}
+	}
+
+	summary.Meals = all.Meals
+	summary.Totals = all.Totals.Rounded()
+	for _, group := range summary.ByMealType {
+		group.Totals = group.Totals.Rounded()
+	}
+	for _, day := range days {
+		day.Totals = day.Totals.Rounded()
+	}
+	return summary
+}
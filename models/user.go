@@ -1,15 +1,156 @@
 package models
 
 import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidHandle   = errors.New("invalid handle")
+	ErrInvalidTimeZone = errors.New("invalid time zone")
+)
+
+// handlePattern matches handles: 3 to 30 lowercase letters, digits, dots or underscores.
+var handlePattern = regexp.MustCompile(`^[a-z0-9._]{3,30}$`)
+
+// reservedHandles would clash with fixed routes under /users.
+var reservedHandles = map[string]bool{
+	"me":       true,
+	"login":    true,
+	"register": true,
+	"delete":   true,
+}
+
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Handle      string      `json:"handle"`
+	Bio         string      `json:"bio"`
+	AvatarURL   string      `json:"avatar_url"`
+	Email       string      `json:"email"`
+	Password    string      `json:"password"`
+	TimeZone    string      `json:"time_zone,omitempty"`
+	Goals       Goals       `json:"goals,omitempty"`
+	MealWindows MealWindows `json:"meal_windows,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// Location returns the time zone of the user, UTC when none is set.
+func (u *User) Location() *time.Location {
+	if u.TimeZone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// UserSummary is the part of a user shown in lists of other users.
+type UserSummary struct {
+	ID     string `json:"id"`
+	Handle string `json:"handle"`
+	Name   string `json:"name"`
+}
+
+// ProfileStats are computed from the listings of a user that the viewer can see.
+type ProfileStats struct {
+	TotalMeals         int      `json:"total_meals"`
+	MostCommonMealType MealType `json:"most_common_meal_type,omitempty"`
+	LikesReceived      int      `json:"likes_received"`
+}
+
+// Profile is the public view of a user.
+type Profile struct {
+	ID        string       `json:"id"`
+	Handle    string       `json:"handle"`
+	Name      string       `json:"name"`
+	AvatarURL string       `json:"avatar_url"`
+	Bio       string       `json:"bio"`
+	JoinedAt  time.Time    `json:"joined_at"`
+	Stats     ProfileStats `json:"stats"`
+}
+
+// ValidHandle reports whether handle may be used as a user handle.
+func ValidHandle(handle string) bool {
+	return handlePattern.MatchString(handle) && !reservedHandles[handle]
+}
+
+// ValidEmail reports whether email is a bare email address, such as "ana@example.com".
+func ValidEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// ValidTimeZone reports whether name is empty or the name of an IANA time zone, such
+// as "Europe/Vilnius".
+func ValidTimeZone(name string) bool {
+	if name == "" {
+		return true
+	}
+	if name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// HandleFromEmail derives a handle candidate from the local part of an email.
+func HandleFromEmail(email string) string {
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+
+	var handle strings.Builder
+	for _, r := range local {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '_' {
+			handle.WriteRune(r)
+		}
+	}
+
+	candidate := handle.String()
+	if len(candidate) > 24 {
+		candidate = candidate[:24]
+	}
+	if len(candidate) < 3 || reservedHandles[candidate] {
+		candidate = "user" + candidate
+	}
+	return candidate
+}
+
+// NewProfile builds the public profile of user with stats over listings.
+func NewProfile(user *User, listings []*Listing) *Profile {
+	profile := &Profile{
+		ID:        user.ID.String(),
+		Handle:    user.Handle,
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+		Bio:       user.Bio,
+		JoinedAt:  user.CreatedAt,
+	}
+
+	mealTypes := make(map[MealType]int)
+	for _, listing := range listings {
+		profile.Stats.TotalMeals++
+		if listing.Type != "" {
+			mealTypes[listing.Type]++
+		}
+		for _, reaction := range listing.Reactions {
+			if reaction.Type == ReactionLike {
+				profile.Stats.LikesReceived++
+			}
+		}
+	}
+
+	for mealType, count := range mealTypes {
+		best := mealTypes[profile.Stats.MostCommonMealType]
+		if count > best || count == best && mealType < profile.Stats.MostCommonMealType {
+			profile.Stats.MostCommonMealType = mealType
+		}
+	}
+	return profile
 }